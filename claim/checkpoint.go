@@ -0,0 +1,103 @@
+package claim
+
+import (
+	"fmt"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+)
+
+// CheckpointUTXO accrual of one utxo at the checkpoint height, Generated and SysFee are the whole GAS
+// of all TotalNEO NEO and Gas the accrued Fixed8
+type CheckpointUTXO struct {
+	UTXO      *rpc.UTXO `json:"utxo"`
+	Generated int64     `json:"generated"`
+	SysFee    int64     `json:"sysFee"`
+	Gas       tx.Fixed8 `json:"gas"`
+}
+
+// Checkpoint claim state of an address at Height, valid as long as the unclaimed utxo set has the
+// same Fingerprint
+type Checkpoint struct {
+	Height      int64             `json:"height"`
+	Fingerprint string            `json:"fingerprint"`
+	UTXOs       []*CheckpointUTXO `json:"utxos"`
+}
+
+// NewCheckpoint checkpoint of the claim breakdown computed at height
+func NewCheckpoint(height int64, fingerprint string, details []*Detail) *Checkpoint {
+	checkpoint := &Checkpoint{
+		Height:      height,
+		Fingerprint: fingerprint,
+		UTXOs:       make([]*CheckpointUTXO, 0, len(details)),
+	}
+
+	for _, detail := range details {
+		checkpoint.UTXOs = append(checkpoint.UTXOs, &CheckpointUTXO{
+			UTXO:      detail.UTXO,
+			Generated: detail.Generated,
+			SysFee:    detail.SysFee,
+			Gas:       detail.Gas,
+		})
+	}
+
+	return checkpoint
+}
+
+// Extend accrue the unspent utxos of checkpoint from its height to tip of source, the checkpoint
+// moves to tip. Return the claim breakdown at tip, the same as Details of the utxos at tip
+func (params *Params) Extend(checkpoint *Checkpoint, source BlockFeeSource, tip int64) ([]*Detail, error) {
+
+	if tip < checkpoint.Height {
+		return nil, fmt.Errorf("checkpoint height %d above tip %d", checkpoint.Height, tip)
+	}
+
+	generated := params.getUnClaimedGas(checkpoint.Height+1, tip+1)
+
+	sysfee, err := rangeFee(source, checkpoint.Height+1, tip+1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	details := make([]*Detail, 0, len(checkpoint.UTXOs))
+
+	for _, accrued := range checkpoint.UTXOs {
+
+		value, err := utxoValue(accrued.UTXO)
+
+		if err != nil {
+			return nil, err
+		}
+
+		detail := &Detail{
+			UTXO:      accrued.UTXO,
+			Value:     value,
+			Start:     accrued.UTXO.Block,
+			End:       accrued.UTXO.SpentBlock,
+			Available: accrued.UTXO.SpentBlock != -1,
+		}
+
+		if !detail.Available {
+			detail.End = tip + 1
+
+			accrued.Generated += generated
+			accrued.SysFee += sysfee
+			accrued.Gas = params.claimGas(value, accrued.Generated+accrued.SysFee)
+		}
+
+		detail.Generated = accrued.Generated
+		detail.SysFee = accrued.SysFee
+		detail.GeneratedGas = params.claimGas(value, detail.Generated)
+		detail.SysFeeGas = params.claimGas(value, detail.SysFee)
+		detail.Gas = accrued.Gas
+
+		accrued.UTXO.Gas = FormatFixed8(detail.Gas)
+
+		details = append(details, detail)
+	}
+
+	checkpoint.Height = tip
+
+	return details, nil
+}
@@ -0,0 +1,63 @@
+package claim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointExtend(t *testing.T) {
+	blocks, _ := varyingBlocks(5000)
+
+	source := NewMemoryFeeSource(blocks)
+
+	at := func(height int64) (BlockFeeSource, []*Detail) {
+		pinned, err := AtHeight(source, height)
+
+		require.NoError(t, err)
+
+		_, details, err := MainNet.Details(RewindUTXOs(sourceUTXOs(), 4000), pinned)
+
+		require.NoError(t, err)
+
+		return pinned, details
+	}
+
+	_, details := at(4000)
+
+	checkpoint := NewCheckpoint(4000, "fingerprint", details)
+
+	// the checkpoint survives the cache round trip
+	data, err := json.Marshal(checkpoint)
+	require.NoError(t, err)
+
+	checkpoint = &Checkpoint{}
+	require.NoError(t, json.Unmarshal(data, checkpoint))
+
+	for _, tip := range []int64{4000, 4001, 4500, 4998} {
+		pinned, expect := at(tip)
+
+		extended, err := MainNet.Extend(checkpoint, pinned, tip)
+
+		require.NoError(t, err)
+		require.Equal(t, tip, checkpoint.Height)
+		require.Len(t, extended, len(expect))
+
+		for i, detail := range extended {
+			require.Equal(t, expect[i].Start, detail.Start)
+			require.Equal(t, expect[i].End, detail.End)
+			require.Equal(t, expect[i].Generated, detail.Generated)
+			require.Equal(t, expect[i].SysFee, detail.SysFee)
+			require.Equal(t, expect[i].GeneratedGas, detail.GeneratedGas)
+			require.Equal(t, expect[i].SysFeeGas, detail.SysFeeGas)
+			require.Equal(t, expect[i].Gas, detail.Gas)
+			require.Equal(t, expect[i].Available, detail.Available)
+			require.Equal(t, expect[i].UTXO.Gas, detail.UTXO.Gas)
+		}
+	}
+
+	_, err = MainNet.Extend(checkpoint, source, 4000)
+
+	require.Error(t, err)
+}
@@ -1,13 +1,9 @@
 package claim
 
 import (
-	"fmt"
-	"math"
 	"sort"
-	"strconv"
 
 	"github.com/inwecrypto/neogo/rpc"
-	"github.com/inwecrypto/neogo/tx"
 )
 
 type utxoSorter []*rpc.UTXO
@@ -22,21 +18,15 @@ func (s utxoSorter) Less(i, j int) bool {
 	return s[i].Block < s[j].Block
 }
 
-var generation = []uint{8, 7, 6, 5, 4, 3, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+func (params *Params) generateGas(id int64) int64 {
 
-const decrementInterval = 2000000
+	step := id / params.DecrementInterval
 
-const totalNEO = 100000000
-
-func generateGas(id int64) float64 {
-
-	step := int(math.Floor(float64(id) / float64(decrementInterval)))
-
-	if step > len(generation) {
+	if step >= int64(len(params.Generation)) {
 		return 0
 	}
 
-	return float64(generation[step])
+	return int64(params.Generation[step])
 }
 
 // GetStartBlock get unclaimed utxos start block number
@@ -51,87 +41,42 @@ func GetStartBlock(unclaimed []*rpc.UTXO) int64 {
 	return unclaimed[0].Block
 }
 
-// GetBlocksFee .
-type GetBlocksFee func(start, end int64) (float64, int64, error)
-
-func getUnClaimedGas(start, end int64) float64 {
+// getUnClaimedGas generated GAS (of all NEO) of blocks in [start, end), summed per decrement
+// interval in O(len(generation)) instead of per block
+func (params *Params) getUnClaimedGas(start, end int64) int64 {
 
-	generated := float64(0)
-
-	for i := start; i < end; i++ {
-		tmp := generateGas(i)
-
-		if tmp == 0 {
-			break
-		}
+	generated := int64(0)
 
-		generated += tmp
+	if start < 0 {
+		start = 0
 	}
 
-	return generated
-}
-
-type blockFeeSorter []*rpc.BlockFee
-
-func (s blockFeeSorter) Len() int {
-	return len(s)
-}
-func (s blockFeeSorter) Swap(i, j int) {
-	s[i], s[j] = s[j], s[i]
-}
-func (s blockFeeSorter) Less(i, j int) bool {
-	return s[i].ID < s[j].ID
-}
-
-// GetUnClaimedGas .
-func GetUnClaimedGas(
-	unclaimed []*rpc.UTXO,
-	getBlocksFee GetBlocksFee) (unavailable, available float64, err error) {
-
-	unavailableFixed8 := tx.MakeFixed8(0)
-	availableFixed8 := tx.MakeFixed8(0)
-
-	for _, utxo := range unclaimed {
-
-		sysfee, end, err := getBlocksFee(utxo.Block, utxo.SpentBlock)
+	if end <= start {
+		return 0
+	}
 
-		start := utxo.Block
+	for step := start / params.DecrementInterval; step < int64(len(params.Generation)); step++ {
+		intervalStart := step * params.DecrementInterval
+		intervalEnd := intervalStart + params.DecrementInterval
 
-		if utxo.SpentBlock != -1 {
-			end++
+		if intervalStart >= end {
+			break
 		}
 
-		gas := sysfee + getUnClaimedGas(start, end)
+		from := start
 
-		val, err := utxo.Value()
-
-		if err != nil {
-			return 0, 0, err
+		if from < intervalStart {
+			from = intervalStart
 		}
 
-		gasFixed8 := tx.MakeFixed8(val * gas / totalNEO)
+		to := end
 
-		if utxo.SpentBlock != -1 {
-			availableFixed8 += gasFixed8
-		} else {
-			unavailableFixed8 += gasFixed8
+		if to > intervalEnd {
+			to = intervalEnd
 		}
 
-		utxo.Gas = gasFixed8.String()
+		generated += (to - from) * int64(params.Generation[step])
 	}
 
-	available = availableFixed8.Float64()
-	unavailable = unavailableFixed8.Float64()
-
-	return
-}
-
-func round(f float64, n int) float64 {
-	data := fmt.Sprintf("%.9f", f)
-
-	data = data[0 : len(data)-1]
-
-	r, _ := strconv.ParseFloat(data, 8)
-
-	return r
+	return generated
 }
@@ -0,0 +1,172 @@
+package claim
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+)
+
+// ClaimTx unsigned claim transaction of claims, Raw is the serialized transaction without witness,
+// SignData is the part the wallet signs and Hash its sha256 digest (the ECDSA message digest)
+type ClaimTx struct {
+	Tx       *tx.Transaction
+	Amount   tx.Fixed8
+	Claims   []*rpc.UTXO
+	Raw      []byte
+	SignData []byte
+	Hash     []byte
+	TxID     string
+}
+
+// BuildClaimTx build the unsigned claim transaction of amount gas to address to, the claims are NEO
+// utxos of the network
+func (params *Params) BuildClaimTx(claims []*rpc.UTXO, amount tx.Fixed8, to string) (*ClaimTx, error) {
+
+	if len(claims) == 0 {
+		return nil, tx.ErrNoUTXO
+	}
+
+	inputs := make(claimInputs, 0, len(claims))
+
+	for _, utxo := range claims {
+		inputs = append(inputs, &tx.Vin{Tx: utxo.TransactionID, N: uint16(utxo.Vout.N)})
+	}
+
+	claimTx := &tx.Transaction{
+		Type:   tx.ClaimTransaction,
+		Extend: &inputs,
+		Outputs: []*tx.Vout{
+			{Asset: params.GasAsset, Value: amount, Address: to},
+		},
+	}
+
+	var signData bytes.Buffer
+
+	if err := writeSignData(&signData, claimTx); err != nil {
+		return nil, err
+	}
+
+	var raw bytes.Buffer
+
+	if err := claimTx.Write(&raw); err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(signData.Bytes())
+
+	txid := sha256.Sum256(hash[:])
+
+	return &ClaimTx{
+		Tx:       claimTx,
+		Amount:   amount,
+		Claims:   claims,
+		Raw:      raw.Bytes(),
+		SignData: signData.Bytes(),
+		Hash:     hash[:],
+		TxID:     hex.EncodeToString(reverseBytes(txid[:])),
+	}, nil
+}
+
+// claimInputs the claims of a claim transaction, its exclusive data
+type claimInputs []*tx.Vin
+
+func (inputs *claimInputs) Write(writer io.Writer) error {
+	length := tx.Varint(len(*inputs))
+
+	if err := length.Write(writer); err != nil {
+		return err
+	}
+
+	for _, vin := range *inputs {
+		if err := vin.Write(writer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (inputs *claimInputs) Read(reader io.Reader) error {
+	var length tx.Varint
+
+	if err := length.Read(reader); err != nil {
+		return err
+	}
+
+	for i := 0; i < int(length); i++ {
+		var vin tx.Vin
+
+		if err := vin.Read(reader); err != nil {
+			return err
+		}
+
+		*inputs = append(*inputs, &vin)
+	}
+
+	return nil
+}
+
+// writeSignData write the unsigned part of transaction, everything but its witnesses
+func writeSignData(writer io.Writer, transaction *tx.Transaction) error {
+	if _, err := writer.Write([]byte{transaction.Type, transaction.Version}); err != nil {
+		return err
+	}
+
+	if transaction.Extend != nil {
+		if err := transaction.Extend.Write(writer); err != nil {
+			return err
+		}
+	}
+
+	length := tx.Varint(len(transaction.Attributes))
+
+	if err := length.Write(writer); err != nil {
+		return err
+	}
+
+	for _, attr := range transaction.Attributes {
+		if err := attr.Write(writer); err != nil {
+			return err
+		}
+	}
+
+	length = tx.Varint(len(transaction.Inputs))
+
+	if err := length.Write(writer); err != nil {
+		return err
+	}
+
+	for _, input := range transaction.Inputs {
+		if err := input.Write(writer); err != nil {
+			return err
+		}
+	}
+
+	length = tx.Varint(len(transaction.Outputs))
+
+	if err := length.Write(writer); err != nil {
+		return err
+	}
+
+	for _, output := range transaction.Outputs {
+		if err := output.Write(writer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func reverseBytes(data []byte) []byte {
+	reversed := make([]byte, len(data))
+
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+
+	return reversed
+}
@@ -0,0 +1,75 @@
+package claim
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildClaimTx(t *testing.T) {
+	claims := []*rpc.UTXO{
+		{
+			TransactionID: "0x3f6b6a2a6b7ef1a8a9b0a7b3f8e6d8c5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9",
+			Vout:          rpc.Vout{Asset: MainNet.NEOAsset, N: 1, Value: "10"},
+			Block:         100,
+			SpentBlock:    200,
+		},
+		{
+			TransactionID: "0x0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+			Vout:          rpc.Vout{Asset: MainNet.NEOAsset, N: 0, Value: "1"},
+			Block:         150,
+			SpentBlock:    300,
+		},
+	}
+
+	// not representable exactly in float64
+	amount := tx.Fixed8(4999999912345679)
+
+	claimTx, err := MainNet.BuildClaimTx(claims, amount, "AanTXadhgdHzGbmy5ZBPXxR4iPHMivzVPZ")
+
+	require.NoError(t, err)
+	require.Equal(t, amount, claimTx.Tx.Outputs[0].Value)
+	require.Equal(t, MainNet.GasAsset, claimTx.Tx.Outputs[0].Asset)
+	require.Equal(t, append(claimTx.SignData, 0x00), claimTx.Raw)
+	require.Equal(t, tx.ClaimTransaction, claimTx.Raw[0])
+	require.Equal(t, byte(len(claims)), claimTx.Raw[2])
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	require.NoError(t, err)
+
+	_, txid, err := claimTx.Tx.Sign(key)
+
+	require.NoError(t, err)
+	require.Equal(t, claimTx.SignData, claimTx.Tx.SignData)
+	require.Equal(t, claimTx.TxID, txid)
+
+	// the sign data leaves out the witness of the signed transaction
+	var signData bytes.Buffer
+
+	require.NoError(t, writeSignData(&signData, claimTx.Tx))
+	require.Equal(t, claimTx.SignData, signData.Bytes())
+
+	// the claims of a private net have its own NEO asset id
+	privnet := *MainNet
+	privnet.NEOAsset = "0x0000000000000000000000000000000000000000000000000000000000000001"
+
+	for _, utxo := range claims {
+		utxo.Vout.Asset = privnet.NEOAsset
+	}
+
+	claimTx, err = privnet.BuildClaimTx(claims, amount, "AanTXadhgdHzGbmy5ZBPXxR4iPHMivzVPZ")
+
+	require.NoError(t, err)
+	require.Equal(t, privnet.NEOAsset, claimTx.Claims[0].Vout.Asset)
+
+	_, err = MainNet.BuildClaimTx(nil, amount, "AanTXadhgdHzGbmy5ZBPXxR4iPHMivzVPZ")
+
+	require.Equal(t, tx.ErrNoUTXO, err)
+}
@@ -0,0 +1,93 @@
+package claim
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Gap missing block heights [From, To]
+type Gap struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// Continuity result of checking block heights cover [Start, End] exactly once
+type Continuity struct {
+	Start      int64   `json:"start"`
+	End        int64   `json:"end"`
+	Gaps       []*Gap  `json:"gaps,omitempty"`
+	Duplicates []int64 `json:"duplicates,omitempty"`
+}
+
+// Complete check if no height is missing or duplicated
+func (continuity *Continuity) Complete() bool {
+	return len(continuity.Gaps) == 0 && len(continuity.Duplicates) == 0
+}
+
+// First the lowest missing or duplicated height, -1 if complete
+func (continuity *Continuity) First() int64 {
+	first := int64(-1)
+
+	if len(continuity.Gaps) > 0 {
+		first = continuity.Gaps[0].From
+	}
+
+	if len(continuity.Duplicates) > 0 && (first == -1 || continuity.Duplicates[0] < first) {
+		first = continuity.Duplicates[0]
+	}
+
+	return first
+}
+
+func (continuity *Continuity) String() string {
+	return fmt.Sprintf("blocks [%d, %d] gaps %d duplicates %d", continuity.Start, continuity.End, len(continuity.Gaps), len(continuity.Duplicates))
+}
+
+// CheckContinuity find missing and duplicate heights in [start, end], heights outside the range are ignored
+func CheckContinuity(heights []int64, start, end int64) *Continuity {
+
+	sorted := make([]int64, 0, len(heights))
+
+	for _, height := range heights {
+		if height >= start && height <= end {
+			sorted = append(sorted, height)
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	continuity := &Continuity{Start: start, End: end}
+
+	next := start
+
+	for i, height := range sorted {
+		if i > 0 && height == sorted[i-1] {
+			if n := len(continuity.Duplicates); n == 0 || continuity.Duplicates[n-1] != height {
+				continuity.Duplicates = append(continuity.Duplicates, height)
+			}
+
+			continue
+		}
+
+		if height > next {
+			continuity.Gaps = append(continuity.Gaps, &Gap{From: next, To: height - 1})
+		}
+
+		next = height + 1
+	}
+
+	if next <= end {
+		continuity.Gaps = append(continuity.Gaps, &Gap{From: next, To: end})
+	}
+
+	return continuity
+}
+
+// IncompleteError the blocks a claim depends on are missing or duplicated
+type IncompleteError struct {
+	Continuity *Continuity
+}
+
+func (err *IncompleteError) Error() string {
+	return fmt.Sprintf("incomplete blocks, %s", err.Continuity)
+}
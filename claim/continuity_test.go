@@ -0,0 +1,69 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckContinuity(t *testing.T) {
+	continuity := CheckContinuity([]int64{3, 1, 2, 2, 6, 9, 9, 9, 12}, 0, 10)
+
+	require.False(t, continuity.Complete())
+	require.Equal(t, []*Gap{{0, 0}, {4, 5}, {7, 8}, {10, 10}}, continuity.Gaps)
+	require.Equal(t, []int64{2, 9}, continuity.Duplicates)
+	require.Equal(t, int64(0), continuity.First())
+
+	continuity = CheckContinuity([]int64{5, 6, 7, 7}, 5, 7)
+
+	require.Empty(t, continuity.Gaps)
+	require.Equal(t, int64(7), continuity.First())
+
+	continuity = CheckContinuity([]int64{4, 5, 6, 7, 8}, 5, 7)
+
+	require.True(t, continuity.Complete())
+	require.Equal(t, int64(-1), continuity.First())
+}
+
+func TestCalcUnclaimedGasIncompleteBlocks(t *testing.T) {
+	utxos := []*rpc.UTXO{
+		{Vout: rpc.Vout{Value: "10"}, Block: 10, SpentBlock: 100},
+	}
+
+	blocks := makeBlocks(10, 100, 100000000)
+
+	_, available, err := MainNet.CalcUnclaimedGas(utxos, NewMemoryFeeSource(blocks))
+
+	require.NoError(t, err)
+	require.NotZero(t, available)
+
+	// missing block 50
+	gap := append(append([]*BlockFee{}, blocks[:40]...), blocks[41:]...)
+
+	_, _, err = MainNet.CalcUnclaimedGas(utxos, NewMemoryFeeSource(gap))
+
+	require.IsType(t, &IncompleteError{}, err)
+	require.Equal(t, []*Gap{{50, 50}}, err.(*IncompleteError).Continuity.Gaps)
+
+	// duplicate block 20
+	duplicate := append(makeBlocks(0, 20, 100000000), blocks[10:]...)
+
+	_, _, err = MainNet.CalcUnclaimedGas(utxos, NewMemoryFeeSource(duplicate))
+
+	require.IsType(t, &IncompleteError{}, err)
+	require.Equal(t, []int64{20}, err.(*IncompleteError).Continuity.Duplicates)
+
+	// blocks before the first loaded one are missing
+	_, _, err = MainNet.CalcUnclaimedGas(utxos, NewMemoryFeeSource(blocks[1:]))
+
+	require.IsType(t, &IncompleteError{}, err)
+	require.Equal(t, []*Gap{{10, 10}}, err.(*IncompleteError).Continuity.Gaps)
+
+	// unspent utxo accrue through the last loaded block
+	utxos[0].SpentBlock = -1
+
+	_, _, err = MainNet.CalcUnclaimedGas(utxos, NewMemoryFeeSource(blocks[:50]))
+
+	require.NoError(t, err)
+}
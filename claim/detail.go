@@ -0,0 +1,116 @@
+package claim
+
+import (
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+)
+
+// Detail claim breakdown of one utxo, Generated and SysFee are the whole GAS of all TotalNEO NEO in
+// [Start, End), GeneratedGas + SysFeeGas == Gas exactly as value / TotalNEO is truncated first
+type Detail struct {
+	UTXO         *rpc.UTXO
+	Value        tx.Fixed8
+	Start        int64
+	End          int64
+	Generated    int64
+	SysFee       int64
+	GeneratedGas tx.Fixed8
+	SysFeeGas    tx.Fixed8
+	Gas          tx.Fixed8
+	Available    bool
+}
+
+// Details claim breakdown of each utxo with sys_fee of source, return the source height. Spent utxos
+// accrue over [block, spentBlock) and unspent ones over [block, height] as the node's CalculateBonus
+func (params *Params) Details(unclaimed []*rpc.UTXO, source BlockFeeSource) (int64, []*Detail, error) {
+
+	height, err := source.Height()
+
+	if err != nil {
+		return -1, nil, err
+	}
+
+	details := make([]*Detail, 0, len(unclaimed))
+
+	for _, utxo := range unclaimed {
+
+		detail := &Detail{
+			UTXO:      utxo,
+			Start:     utxo.Block,
+			End:       utxo.SpentBlock,
+			Available: utxo.SpentBlock != -1,
+		}
+
+		if detail.End == -1 {
+			detail.End = height + 1
+		}
+
+		sysfee, err := rangeFee(source, detail.Start, detail.End)
+
+		if err != nil {
+			return height, nil, err
+		}
+
+		value, err := utxoValue(utxo)
+
+		if err != nil {
+			return height, nil, err
+		}
+
+		detail.Value = value
+		detail.SysFee = sysfee
+		detail.Generated = params.getUnClaimedGas(detail.Start, detail.End)
+		detail.GeneratedGas = params.claimGas(value, detail.Generated)
+		detail.SysFeeGas = params.claimGas(value, detail.SysFee)
+		detail.Gas = params.claimGas(value, detail.Generated+detail.SysFee)
+
+		utxo.Gas = FormatFixed8(detail.Gas)
+
+		details = append(details, detail)
+	}
+
+	return height, details, nil
+}
+
+// SumDetails total unavailable and available gas of the breakdown
+func SumDetails(details []*Detail) (unavailable, available tx.Fixed8) {
+	for _, detail := range details {
+		if detail.Available {
+			available += detail.Gas
+		} else {
+			unavailable += detail.Gas
+		}
+	}
+
+	return
+}
+
+// Weight sum of value / TotalNEO of the unspent utxos, the unavailable gas of the breakdown grows by
+// Weight for each GAS (generated plus sys_fee of all TotalNEO NEO) accrued by new blocks
+func (params *Params) Weight(details []*Detail) int64 {
+	weight := int64(0)
+
+	for _, detail := range details {
+		if !detail.Available {
+			weight += int64(detail.Value) / params.TotalNEO
+		}
+	}
+
+	return weight
+}
+
+// Accrue the GAS of all TotalNEO NEO accrued by blocks (from, to] of source, the unavailable gas at to
+// is the unavailable gas at from plus Weight * Accrue
+func (params *Params) Accrue(source BlockFeeSource, from, to int64) (int64, error) {
+	if to <= from {
+		return 0, nil
+	}
+
+	sysfee, err := rangeFee(source, from+1, to+1)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return params.getUnClaimedGas(from+1, to+1) + sysfee, nil
+}
@@ -0,0 +1,94 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetails(t *testing.T) {
+	// 3 GAS sys_fee per block
+	source := &amountSource{height: 2099999, amount: func(height int64) (int64, error) {
+		return (height + 1) * 3, nil
+	}}
+
+	utxos := makeUTXOs(50, 2100000)
+
+	for i, utxo := range utxos {
+		if i%2 == 0 {
+			utxo.SpentBlock = utxo.Block + int64(i)*1000
+		}
+	}
+
+	unavailable, available, err := MainNet.CalcUnclaimedGas(utxos, source)
+
+	require.NoError(t, err)
+
+	height, details, err := MainNet.Details(utxos, source)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(2099999), height)
+	require.Len(t, details, len(utxos))
+
+	var sumAvailable, sumUnavailable tx.Fixed8
+
+	for _, detail := range details {
+		require.Equal(t, detail.Gas, detail.GeneratedGas+detail.SysFeeGas)
+		require.Equal(t, (detail.End-detail.Start)*3, detail.SysFee)
+		require.Equal(t, FormatFixed8(detail.Gas), detail.UTXO.Gas)
+		require.Equal(t, detail.UTXO.SpentBlock != -1, detail.Available)
+
+		if detail.Available {
+			sumAvailable += detail.Gas
+		} else {
+			sumUnavailable += detail.Gas
+			require.Equal(t, int64(2100000), detail.End)
+		}
+	}
+
+	require.Equal(t, available, sumAvailable)
+	require.Equal(t, unavailable, sumUnavailable)
+
+	_, _, err = MainNet.Details([]*rpc.UTXO{{Vout: rpc.Vout{Value: "x"}, Block: 1, SpentBlock: -1}}, source)
+
+	require.Error(t, err)
+}
+
+func TestAccrue(t *testing.T) {
+	blocks, _ := varyingBlocks(5000)
+
+	source := NewMemoryFeeSource(blocks)
+
+	utxos := sourceUTXOs()
+
+	at := func(height int64) []*Detail {
+		pinned, err := AtHeight(source, height)
+
+		require.NoError(t, err)
+
+		_, details, err := MainNet.Details(RewindUTXOs(utxos, height), pinned)
+
+		require.NoError(t, err)
+
+		return details
+	}
+
+	from := at(4000)
+	to := at(4998)
+
+	accrued, err := MainNet.Accrue(source, 4000, 4998)
+
+	require.NoError(t, err)
+
+	fromUnavailable, _ := SumDetails(from)
+	toUnavailable, _ := SumDetails(to)
+
+	require.Equal(t, toUnavailable, fromUnavailable+tx.Fixed8(MainNet.Weight(from)*accrued))
+
+	accrued, err = MainNet.Accrue(source, 4000, 4000)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(0), accrued)
+}
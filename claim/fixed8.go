@@ -0,0 +1,69 @@
+package claim
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+)
+
+// fixed8One Fixed8 raw value of 1
+const fixed8One = 100000000
+
+var bigFixed8One = big.NewInt(fixed8One)
+
+// ParseFixed8 parse decimal string (as stored by the indexer) into Fixed8 without going through float64,
+// digits beyond 8 decimals are truncated
+func ParseFixed8(value string) (tx.Fixed8, error) {
+
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+
+	if !ok {
+		return 0, fmt.Errorf("invalid decimal %s", value)
+	}
+
+	r.Mul(r, new(big.Rat).SetInt(bigFixed8One))
+
+	raw := new(big.Int).Quo(r.Num(), r.Denom())
+
+	if !raw.IsInt64() {
+		return 0, fmt.Errorf("decimal %s overflow Fixed8", value)
+	}
+
+	return tx.Fixed8(raw.Int64()), nil
+}
+
+// FormatFixed8 format Fixed8 as decimal string with 8 decimals, tx.Fixed8.String goes through
+// float64 and loses precision for large values
+func FormatFixed8(value tx.Fixed8) string {
+	raw := int64(value)
+
+	sign := ""
+
+	if raw < 0 {
+		sign = "-"
+		raw = -raw
+	}
+
+	return fmt.Sprintf("%s%d.%08d", sign, raw/fixed8One, raw%fixed8One)
+}
+
+// WholeGas truncate Fixed8 to whole GAS as the node does with (long)Fixed8 when it sums block sys_fee
+func WholeGas(value tx.Fixed8) int64 {
+	return int64(value) / fixed8One
+}
+
+// utxoValue exact Fixed8 value of utxo
+func utxoValue(utxo *rpc.UTXO) (tx.Fixed8, error) {
+	return ParseFixed8(utxo.Vout.Value)
+}
+
+// claimGas claimable gas of value NEO for amount (generated plus sys_fee GAS of all TotalNEO NEO),
+// same as the node's `Sum(value) / 100000000 * amount` in Fixed8 arithmetic
+func (params *Params) claimGas(value tx.Fixed8, amount int64) tx.Fixed8 {
+	gas := new(big.Int).Mul(big.NewInt(int64(value)/params.TotalNEO), big.NewInt(amount))
+
+	return tx.Fixed8(gas.Int64())
+}
@@ -0,0 +1,120 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/inwecrypto/neogo/tx"
+	"github.com/stretchr/testify/require"
+)
+
+// nodeCalculateBonus port of neo 2.x Blockchain.CalculateBonus for one (start, end) group, a cross
+// check for ranges without a recorded node response, the responses in testdata are the reference
+func nodeCalculateBonus(value tx.Fixed8, start, end uint32, sysFeeAmount func(height uint32) int64) tx.Fixed8 {
+	amount := int64(0)
+
+	ustart := start / uint32(MainNet.DecrementInterval)
+
+	if ustart < uint32(len(MainNet.Generation)) {
+		istart := start % uint32(MainNet.DecrementInterval)
+		uend := end / uint32(MainNet.DecrementInterval)
+		iend := end % uint32(MainNet.DecrementInterval)
+
+		if uend >= uint32(len(MainNet.Generation)) {
+			uend = uint32(len(MainNet.Generation))
+			iend = 0
+		}
+
+		if iend == 0 {
+			uend--
+			iend = uint32(MainNet.DecrementInterval)
+		}
+
+		for ustart < uend {
+			amount += int64(uint32(MainNet.DecrementInterval)-istart) * int64(MainNet.Generation[ustart])
+			ustart++
+			istart = 0
+		}
+
+		amount += int64(iend-istart) * int64(MainNet.Generation[ustart])
+	}
+
+	startFee := int64(0)
+
+	if start != 0 {
+		startFee = sysFeeAmount(start - 1)
+	}
+
+	amount += sysFeeAmount(end-1) - startFee
+
+	return tx.Fixed8(int64(value) / 100000000 * amount)
+}
+
+func TestParseFixed8(t *testing.T) {
+	for value, expect := range map[string]tx.Fixed8{
+		"0":                  0,
+		"1":                  100000000,
+		"0.00000001":         1,
+		"0.000000019":        1,
+		"49999999.12345678":  4999999912345678,
+		"-1.5":               -150000000,
+		"1e-05":              1000,
+		" 100000000 ":        10000000000000000,
+		"92233720368.547758": 9223372036854775800,
+	} {
+		parsed, err := ParseFixed8(value)
+
+		require.NoError(t, err, value)
+		require.Equal(t, expect, parsed, value)
+	}
+
+	_, err := ParseFixed8("92233720369")
+
+	require.Error(t, err)
+
+	_, err = ParseFixed8("abc")
+
+	require.Error(t, err)
+}
+
+func TestFormatFixed8(t *testing.T) {
+	require.Equal(t, "0.00000000", FormatFixed8(0))
+	require.Equal(t, "0.00000008", FormatFixed8(8))
+	require.Equal(t, "-1.50000000", FormatFixed8(-150000000))
+	// float64 formatting of tx.Fixed8.String rounds this value
+	require.Equal(t, "92233720368.54775807", FormatFixed8(9223372036854775807))
+}
+
+func makeBlocks(start, end int64, fee tx.Fixed8) []*BlockFee {
+	blocks := make([]*BlockFee, 0, end-start+1)
+
+	for i := start; i <= end; i++ {
+		blocks = append(blocks, &BlockFee{Block: i, SysFee: fee})
+	}
+
+	return blocks
+}
+
+func TestClaimGoldenLargeHolder(t *testing.T) {
+	noFee := func(height uint32) int64 { return 0 }
+
+	for _, golden := range []struct {
+		value  string
+		start  int64
+		end    int64
+		expect string
+	}{
+		{"1000000", 1000000, 3000000, "150000.00000000"},
+		{"12345678", 1234567, 5432109, "3545199.46238004"},
+		{"49999999", 0, 44000000, "49999999.00000000"},
+		{"49999999", 0, 50000000, "49999999.00000000"},
+	} {
+		value, err := ParseFixed8(golden.value)
+
+		require.NoError(t, err)
+
+		gas := MainNet.claimGas(value, MainNet.getUnClaimedGas(golden.start, golden.end))
+
+		require.Equal(t, golden.expect, FormatFixed8(gas), "%v", golden)
+		require.Equal(t, nodeCalculateBonus(value, uint32(golden.start), uint32(golden.end), noFee), gas, "%v", golden)
+	}
+}
@@ -0,0 +1,110 @@
+package claim
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// getUnClaimedGasLoop the per block implementation getUnClaimedGas replaced, kept as reference
+func getUnClaimedGasLoop(start, end int64) int64 {
+
+	generated := int64(0)
+
+	for i := start; i < end; i++ {
+		tmp := MainNet.generateGas(i)
+
+		if tmp == 0 {
+			break
+		}
+
+		generated += tmp
+	}
+
+	return generated
+}
+
+func TestGetUnClaimedGasBoundaries(t *testing.T) {
+	const window = 40
+
+	totalBlocks := int64(len(MainNet.Generation)) * MainNet.DecrementInterval
+
+	for step := int64(0); step <= int64(len(MainNet.Generation)); step++ {
+		boundary := step * MainNet.DecrementInterval
+
+		for start := boundary - window; start <= boundary+window; start++ {
+			if start < 0 {
+				continue
+			}
+
+			for end := start - 1; end <= boundary+window; end++ {
+				require.Equal(t, getUnClaimedGasLoop(start, end), MainNet.getUnClaimedGas(start, end), "[%d, %d)", start, end)
+			}
+		}
+	}
+
+	require.Equal(t, int64(100000000), MainNet.getUnClaimedGas(0, totalBlocks))
+	require.Equal(t, int64(100000000), MainNet.getUnClaimedGas(0, totalBlocks*2))
+	require.Equal(t, int64(0), MainNet.getUnClaimedGas(totalBlocks, totalBlocks+1))
+}
+
+func TestGetUnClaimedGasRandom(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	totalBlocks := int64(len(MainNet.Generation)) * MainNet.DecrementInterval
+
+	for i := 0; i < 200; i++ {
+		start := random.Int63n(totalBlocks + MainNet.DecrementInterval)
+		end := start + random.Int63n(3*MainNet.DecrementInterval)
+
+		require.Equal(t, getUnClaimedGasLoop(start, end), MainNet.getUnClaimedGas(start, end), "[%d, %d)", start, end)
+	}
+}
+
+func makeUTXOs(count int, tip int64) []*rpc.UTXO {
+	random := rand.New(rand.NewSource(int64(count)))
+
+	utxos := make([]*rpc.UTXO, 0, count)
+
+	for i := 0; i < count; i++ {
+		utxos = append(utxos, &rpc.UTXO{
+			Vout:       rpc.Vout{Asset: NEOAssert, Value: fmt.Sprintf("%d", random.Int63n(1000)+1)},
+			Block:      random.Int63n(tip),
+			SpentBlock: -1,
+		})
+	}
+
+	return utxos
+}
+
+func benchmarkGenerated(b *testing.B, count int, generated func(start, end int64) int64) {
+	const tip = 2600000
+
+	utxos := makeUTXOs(count, tip)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, utxo := range utxos {
+			val, _ := utxoValue(utxo)
+			MainNet.claimGas(val, generated(utxo.Block, tip))
+		}
+	}
+}
+
+func BenchmarkGeneratedLoop10(b *testing.B) { benchmarkGenerated(b, 10, getUnClaimedGasLoop) }
+
+func BenchmarkGeneratedLoop300(b *testing.B) { benchmarkGenerated(b, 300, getUnClaimedGasLoop) }
+
+func BenchmarkGeneratedClosedForm10(b *testing.B) { benchmarkGenerated(b, 10, MainNet.getUnClaimedGas) }
+
+func BenchmarkGeneratedClosedForm300(b *testing.B) {
+	benchmarkGenerated(b, 300, MainNet.getUnClaimedGas)
+}
+
+func BenchmarkGeneratedClosedForm1000(b *testing.B) {
+	benchmarkGenerated(b, 1000, MainNet.getUnClaimedGas)
+}
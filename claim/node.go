@@ -0,0 +1,99 @@
+package claim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ybbus/jsonrpc"
+)
+
+// NodeFeeSource BlockFeeSource reading a neo node by getblockcount and getblocksysfee, the node's
+// amounts are the reference the other sources must agree with
+type NodeFeeSource struct {
+	mutex   sync.Mutex
+	client  *jsonrpc.RPCClient
+	amounts map[int64]int64
+}
+
+// NewNodeFeeSource create source of the node rpc url
+func NewNodeFeeSource(url string, timeout time.Duration) *NodeFeeSource {
+	client := jsonrpc.NewRPCClient(url)
+
+	client.SetHTTPClient(&http.Client{Timeout: timeout})
+
+	return &NodeFeeSource{
+		client:  client,
+		amounts: make(map[int64]int64),
+	}
+}
+
+// Height the node height, block count minus 1
+func (source *NodeFeeSource) Height() (int64, error) {
+	response, err := source.client.Call("getblockcount")
+
+	if err != nil {
+		return -1, err
+	}
+
+	if response.Error != nil {
+		return -1, fmt.Errorf("getblockcount err, %d %s", response.Error.Code, response.Error.Message)
+	}
+
+	count, err := response.GetInt64()
+
+	if err != nil {
+		return -1, err
+	}
+
+	return count - 1, nil
+}
+
+// SysFeeAmount the node's GetSysFeeAmount of height, amounts are immutable and cached
+func (source *NodeFeeSource) SysFeeAmount(height int64) (int64, error) {
+
+	if height == -1 {
+		return 0, nil
+	}
+
+	source.mutex.Lock()
+	amount, ok := source.amounts[height]
+	source.mutex.Unlock()
+
+	if ok {
+		return amount, nil
+	}
+
+	response, err := source.client.Call("getblocksysfee", height)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if response.Error != nil {
+		return 0, fmt.Errorf("getblocksysfee %d err, %d %s", height, response.Error.Code, response.Error.Message)
+	}
+
+	// neo 2.x returns the amount as string, accept number too
+	var result json.RawMessage
+
+	if err := response.GetObject(&result); err != nil {
+		return 0, err
+	}
+
+	amount, err = strconv.ParseInt(strings.Trim(string(result), `"`), 10, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("getblocksysfee %d result %s err, %s", height, result, err)
+	}
+
+	source.mutex.Lock()
+	source.amounts[height] = amount
+	source.mutex.Unlock()
+
+	return amount, nil
+}
@@ -0,0 +1,103 @@
+package claim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Params network profile of the claim calculation, the GAS generation schedule and the governing
+// (NEO) and utility (GAS) asset ids of the chain
+type Params struct {
+	Name              string `json:"name"`
+	Generation        []uint `json:"generation"`
+	DecrementInterval int64  `json:"decrementInterval"`
+	TotalNEO          int64  `json:"totalNEO"`
+	NEOAsset          string `json:"neoAsset"`
+	GasAsset          string `json:"gasAsset"`
+}
+
+// MainNet neo 2.x mainnet profile
+var MainNet = &Params{
+	Name:              "mainnet",
+	Generation:        []uint{8, 7, 6, 5, 4, 3, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+	DecrementInterval: 2000000,
+	TotalNEO:          100000000,
+	NEOAsset:          "0xc56f33fc6ecfcd0c225c4ab356fee59390af8560be0e930faebe74a6daff7c9b",
+	GasAsset:          "0x602c79718b16e442de58778e148d0b1084e3b2dffd5de6b7b16cee7969282de7",
+}
+
+// TestNet neo 2.x testnet profile, it shares the mainnet schedule and genesis assets
+var TestNet = &Params{
+	Name:              "testnet",
+	Generation:        MainNet.Generation,
+	DecrementInterval: MainNet.DecrementInterval,
+	TotalNEO:          MainNet.TotalNEO,
+	NEOAsset:          MainNet.NEOAsset,
+	GasAsset:          MainNet.GasAsset,
+}
+
+var profiles = map[string]*Params{
+	MainNet.Name: MainNet,
+	TestNet.Name: TestNet,
+}
+
+// GetParams get built-in profile by name
+func GetParams(name string) (*Params, bool) {
+	params, ok := profiles[strings.ToLower(name)]
+
+	return params, ok
+}
+
+// ParseParams parse custom profile from json and validate it
+func ParseParams(data []byte) (*Params, error) {
+	params := &Params{}
+
+	if err := json.Unmarshal(data, params); err != nil {
+		return nil, err
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// Validate check the profile is usable by the calculation
+func (params *Params) Validate() error {
+	if len(params.Generation) == 0 {
+		return fmt.Errorf("network %s: generation is empty", params.Name)
+	}
+
+	if params.DecrementInterval <= 0 {
+		return fmt.Errorf("network %s: decrementInterval must be positive", params.Name)
+	}
+
+	if params.TotalNEO <= 0 {
+		return fmt.Errorf("network %s: totalNEO must be positive", params.Name)
+	}
+
+	if !isAssetID(params.NEOAsset) || !isAssetID(params.GasAsset) {
+		return fmt.Errorf("network %s: asset id must be 0x prefixed 32 bytes hex", params.Name)
+	}
+
+	params.NEOAsset = strings.ToLower(params.NEOAsset)
+	params.GasAsset = strings.ToLower(params.GasAsset)
+
+	return nil
+}
+
+func isAssetID(id string) bool {
+	if len(id) != 66 || !strings.HasPrefix(id, "0x") {
+		return false
+	}
+
+	for _, c := range strings.ToLower(id[2:]) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
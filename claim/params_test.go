@@ -0,0 +1,46 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseParams(t *testing.T) {
+	params, err := ParseParams([]byte(`{
+		"name": "privnet",
+		"generation": [100, 50],
+		"decrementInterval": 10,
+		"totalNEO": 1000,
+		"neoAsset": "0xC56F33FC6ECFCD0C225C4AB356FEE59390AF8560BE0E930FAEBE74A6DAFF7C9B",
+		"gasAsset": "0x602c79718b16e442de58778e148d0b1084e3b2dffd5de6b7b16cee7969282de7"
+	}`))
+
+	require.NoError(t, err)
+	require.Equal(t, MainNet.NEOAsset, params.NEOAsset)
+
+	require.Equal(t, int64(100*10+50*5), params.getUnClaimedGas(0, 15))
+	require.Equal(t, int64(100*10+50*10), params.getUnClaimedGas(0, 100))
+
+	// 1 of 1000 NEO
+	require.Equal(t, "0.75000000", FormatFixed8(params.claimGas(100000000, params.getUnClaimedGas(5, 15))))
+
+	_, err = ParseParams([]byte(`{"name": "privnet", "generation": [1], "decrementInterval": 10, "totalNEO": 1000}`))
+
+	require.Error(t, err)
+
+	_, err = ParseParams([]byte(`{"name": "privnet", "decrementInterval": 10, "totalNEO": 1000}`))
+
+	require.Error(t, err)
+}
+
+func TestGetParams(t *testing.T) {
+	params, ok := GetParams("TestNet")
+
+	require.True(t, ok)
+	require.Equal(t, TestNet, params)
+
+	_, ok = GetParams("privnet")
+
+	require.False(t, ok)
+}
@@ -0,0 +1,120 @@
+package claim
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+)
+
+// claim transaction layout, sizes in bytes
+const (
+	claimInputSize = 34 // prev txid + index
+	claimTxFixed   = 66 // type, version, empty attributes/inputs, one gas output and the witness count
+	// SingleSigWitnessSize witness of a single signature account, signature push plus CHECKSIG redeem script
+	SingleSigWitnessSize = 102
+)
+
+// PlanLimits limits of one claim transaction, MaxBytes includes WitnessSize, zero means unlimited
+type PlanLimits struct {
+	MaxClaims   int
+	MaxBytes    int
+	WitnessSize int
+}
+
+func varintSize(n int) int {
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	case n <= 0xffffffff:
+		return 5
+	default:
+		return 9
+	}
+}
+
+// ClaimTxSize serialized size of an unsigned claim transaction of n claims
+func ClaimTxSize(n int) int {
+	return claimTxFixed + varintSize(n) + claimInputSize*n
+}
+
+// PlanClaimTxs partition the available utxos of details (oldest first) into claim transactions under
+// limits, each transaction claims the exact gas of its utxos so the amounts sum to the available gas.
+// Utxos without gas are left out
+func (params *Params) PlanClaimTxs(details []*Detail, to string, limits *PlanLimits) ([]*ClaimTx, error) {
+
+	if limits.MaxBytes > 0 && ClaimTxSize(1)+limits.WitnessSize > limits.MaxBytes {
+		return nil, fmt.Errorf("max bytes %d can not hold a claim transaction", limits.MaxBytes)
+	}
+
+	available := make([]*Detail, 0, len(details))
+
+	for _, detail := range details {
+		if detail.Available && detail.Gas > 0 {
+			available = append(available, detail)
+		}
+	}
+
+	sort.SliceStable(available, func(i, j int) bool {
+		return available[i].Start < available[j].Start
+	})
+
+	plans := make([]*ClaimTx, 0)
+
+	var batch []*Detail
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		claims, amount := batchClaims(batch)
+
+		claimTx, err := params.BuildClaimTx(claims, amount, to)
+
+		if err != nil {
+			return err
+		}
+
+		plans = append(plans, claimTx)
+
+		batch = nil
+
+		return nil
+	}
+
+	for _, detail := range available {
+		n := len(batch) + 1
+
+		if (limits.MaxClaims > 0 && n > limits.MaxClaims) ||
+			(limits.MaxBytes > 0 && ClaimTxSize(n)+limits.WitnessSize > limits.MaxBytes) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+
+		batch = append(batch, detail)
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return plans, nil
+}
+
+func batchClaims(batch []*Detail) ([]*rpc.UTXO, tx.Fixed8) {
+	claims := make([]*rpc.UTXO, 0, len(batch))
+
+	amount := tx.Fixed8(0)
+
+	for _, detail := range batch {
+		claims = append(claims, detail.UTXO)
+		amount += detail.Gas
+	}
+
+	return claims, amount
+}
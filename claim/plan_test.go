@@ -0,0 +1,83 @@
+package claim
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+	"github.com/stretchr/testify/require"
+)
+
+func makeClaimDetails(count int) []*Detail {
+	details := make([]*Detail, 0, count)
+
+	for i := 0; i < count; i++ {
+		details = append(details, &Detail{
+			UTXO: &rpc.UTXO{
+				TransactionID: fmt.Sprintf("0x%064x", i+1),
+				Vout:          rpc.Vout{Asset: MainNet.NEOAsset, N: i % 3, Value: "1"},
+			},
+			Start:     int64(count - i),
+			Gas:       tx.Fixed8(i + 1),
+			Available: i%10 != 9,
+		})
+	}
+
+	return details
+}
+
+func TestPlanClaimTxs(t *testing.T) {
+	details := makeClaimDetails(1000)
+
+	_, total := SumDetails(details)
+
+	limits := &PlanLimits{MaxClaims: 200, MaxBytes: 102400, WitnessSize: SingleSigWitnessSize}
+
+	plans, err := MainNet.PlanClaimTxs(details, "AanTXadhgdHzGbmy5ZBPXxR4iPHMivzVPZ", limits)
+
+	require.NoError(t, err)
+	require.Len(t, plans, 5)
+
+	sum := tx.Fixed8(0)
+	claims := 0
+	last := int64(-1)
+
+	for _, plan := range plans {
+		require.True(t, len(plan.Claims) <= limits.MaxClaims)
+		require.Equal(t, ClaimTxSize(len(plan.Claims)), len(plan.Raw))
+
+		sum += plan.Amount
+		claims += len(plan.Claims)
+
+		require.True(t, plan.Tx.Outputs[0].Value == plan.Amount)
+	}
+
+	require.Equal(t, total, sum)
+	require.Equal(t, 900, claims)
+
+	// oldest first
+	for _, plan := range plans {
+		for _, utxo := range plan.Claims {
+			for _, detail := range details {
+				if detail.UTXO == utxo {
+					require.True(t, detail.Available)
+					require.True(t, detail.Start > last)
+					last = detail.Start
+				}
+			}
+		}
+	}
+
+	// byte limit of 10 claims
+	limits = &PlanLimits{MaxBytes: ClaimTxSize(10) + SingleSigWitnessSize, WitnessSize: SingleSigWitnessSize}
+
+	plans, err = MainNet.PlanClaimTxs(details, "AanTXadhgdHzGbmy5ZBPXxR4iPHMivzVPZ", limits)
+
+	require.NoError(t, err)
+	require.Len(t, plans, 90)
+
+	_, err = MainNet.PlanClaimTxs(details, "AanTXadhgdHzGbmy5ZBPXxR4iPHMivzVPZ", &PlanLimits{MaxBytes: 100})
+
+	require.Error(t, err)
+}
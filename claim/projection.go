@@ -0,0 +1,42 @@
+package claim
+
+import "github.com/inwecrypto/neogo/tx"
+
+// Projection unavailable gas of unspent utxos projected to a target block, assuming the utxos stay
+// unspent. Accrued is the gas up to end, Generated and SysFee are the gas of [end, target)
+type Projection struct {
+	Accrued   tx.Fixed8
+	Generated tx.Fixed8
+	SysFee    tx.Fixed8
+}
+
+// Unavailable projected unavailable gas at target height
+func (projection *Projection) Unavailable() tx.Fixed8 {
+	return projection.Accrued + projection.Generated + projection.SysFee
+}
+
+// Project project the unspent utxos of details, which accrue to end (exclusive), to target (exclusive),
+// sysFee is the estimated sys_fee of blocks [end, target) in whole GAS. Details computed through tip
+// project blocks (tip, height] with end tip+1 and target height+1
+func (params *Params) Project(details []*Detail, end, target, sysFee int64) *Projection {
+
+	projection := &Projection{}
+
+	generated := params.getUnClaimedGas(end, target)
+
+	if target <= end {
+		sysFee = 0
+	}
+
+	for _, detail := range details {
+		if detail.Available {
+			continue
+		}
+
+		projection.Accrued += detail.Gas
+		projection.Generated += params.claimGas(detail.Value, generated)
+		projection.SysFee += params.claimGas(detail.Value, sysFee)
+	}
+
+	return projection
+}
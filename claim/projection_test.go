@@ -0,0 +1,59 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProject(t *testing.T) {
+	noFee := func(height int64) (int64, error) { return 0, nil }
+
+	at := func(height int64, amount func(height int64) (int64, error)) BlockFeeSource {
+		return &amountSource{height: height, amount: amount}
+	}
+
+	utxos := makeUTXOs(20, 1000000)
+
+	utxos[0].SpentBlock = utxos[0].Block + 10
+
+	const tip, target = 1000000, 2500000
+
+	// details accrue through tip - 1, to tip (exclusive)
+	_, details, err := MainNet.Details(utxos, at(tip-1, noFee))
+
+	require.NoError(t, err)
+
+	projection := MainNet.Project(details, tip, target, 0)
+
+	// without sys_fee the projection equals computing to target directly
+	_, expected, err := MainNet.Details(utxos, at(target-1, noFee))
+
+	require.NoError(t, err)
+
+	unavailable, _ := SumDetails(expected)
+
+	require.Equal(t, unavailable, projection.Unavailable())
+	require.Zero(t, projection.SysFee)
+
+	// 1 GAS sys_fee per block of the projected range
+	projection = MainNet.Project(details, tip, target, target-tip)
+
+	perBlock := func(height int64) (int64, error) {
+		if height < tip {
+			return 0, nil
+		}
+
+		return height - tip + 1, nil
+	}
+
+	_, expected, err = MainNet.Details(utxos, at(target-1, perBlock))
+
+	require.NoError(t, err)
+
+	unavailable, _ = SumDetails(expected)
+
+	require.Equal(t, unavailable, projection.Unavailable())
+
+	require.Zero(t, MainNet.Project(details, tip, tip, 100).SysFee)
+}
@@ -0,0 +1,276 @@
+package claim
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ybbus/jsonrpc"
+)
+
+var (
+	recordNode      = flag.String("record.node", "", "neo 2.x node rpc url TestRecordNodeClaims records from")
+	recordAddresses = flag.String("record.addresses", "", "comma separated addresses TestRecordNodeClaims records")
+	recordNetwork   = flag.String("record.network", "MainNet", "network of the recorded node")
+)
+
+// recordUsage how to record the missing node responses
+const recordUsage = "record them from a neo 2.x node with the RpcSystemAssetTracker plugin, " +
+	"go test ./claim -run TestRecordNodeClaims -record.node <url> -record.addresses <addresses>"
+
+// errHeightMoved the node added a block while an address was recorded
+var errHeightMoved = errors.New("node height moved")
+
+// recordingNode the node rpc calls of the recorder
+type recordingNode struct {
+	url    string
+	client *jsonrpc.RPCClient
+	source *NodeFeeSource
+}
+
+func (node *recordingNode) call(result interface{}, method string, params ...interface{}) error {
+	response, err := node.client.Call(method, params...)
+
+	if err != nil {
+		return err
+	}
+
+	if response.Error != nil {
+		return fmt.Errorf("%s err, %d %s", method, response.Error.Code, response.Error.Message)
+	}
+
+	return response.GetObject(result)
+}
+
+// startHeight the height of the block of transaction txid
+func (node *recordingNode) startHeight(txid string) (int64, error) {
+	var transaction struct {
+		BlockHash string `json:"blockhash"`
+	}
+
+	if err := node.call(&transaction, "getrawtransaction", txid, 1); err != nil {
+		return 0, err
+	}
+
+	var header struct {
+		Index int64 `json:"index"`
+	}
+
+	if err := node.call(&header, "getblockheader", transaction.BlockHash, true); err != nil {
+		return 0, err
+	}
+
+	return header.Index, nil
+}
+
+// sysFee the getblocksysfee amounts of heights
+func (node *recordingNode) sysFee(heights []int64) (map[string]int64, error) {
+	amounts := make(map[string]int64, len(heights))
+
+	for _, height := range heights {
+		if height == -1 {
+			continue
+		}
+
+		amount, err := node.source.SysFeeAmount(height)
+
+		if err != nil {
+			return nil, err
+		}
+
+		amounts[strconv.FormatInt(height, 10)] = amount
+	}
+
+	return amounts, nil
+}
+
+// recordClaimable getclaimable of address with the getblocksysfee amounts the node claims its entries
+// with, at one node height
+func (node *recordingNode) recordClaimable(address string) (*nodeFixture, error) {
+	height, err := node.source.Height()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var claimable json.RawMessage
+
+	if err := node.call(&claimable, "getclaimable", address); err != nil {
+		return nil, err
+	}
+
+	if after, err := node.source.Height(); err != nil || after != height {
+		if err == nil {
+			err = errHeightMoved
+		}
+
+		return nil, err
+	}
+
+	var result struct {
+		Claimable []*nodeClaimable `json:"claimable"`
+	}
+
+	if err := json.Unmarshal(claimable, &result); err != nil {
+		return nil, err
+	}
+
+	heights := make([]int64, 0, 2*len(result.Claimable))
+
+	for _, entry := range result.Claimable {
+		heights = append(heights, entry.StartHeight-1, entry.EndHeight-1)
+	}
+
+	fixture := &nodeFixture{
+		Network:  *recordNetwork,
+		Recorded: fmt.Sprintf("%s at %s", node.url, time.Now().UTC().Format(time.RFC3339)),
+		Method:   "getclaimable",
+		Params:   []string{address},
+		Height:   height,
+		Result:   claimable,
+	}
+
+	if fixture.SysFee, err = node.sysFee(heights); err != nil {
+		return nil, err
+	}
+
+	return fixture, nil
+}
+
+// recordUnclaimed getunclaimed of address with its getunspents NEO utxos, their start heights and the
+// getblocksysfee amounts the node accrues them with, all at one node height
+func (node *recordingNode) recordUnclaimed(params *Params, address string) (*nodeFixture, error) {
+	height, err := node.source.Height()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var unclaimed json.RawMessage
+
+	if err := node.call(&unclaimed, "getunclaimed", address); err != nil {
+		return nil, err
+	}
+
+	var unspents struct {
+		Balance []struct {
+			AssetHash string           `json:"asset_hash"`
+			Unspent   []*nodeClaimable `json:"unspent"`
+		} `json:"balance"`
+	}
+
+	if err := node.call(&unspents, "getunspents", address); err != nil {
+		return nil, err
+	}
+
+	if after, err := node.source.Height(); err != nil || after != height {
+		if err == nil {
+			err = errHeightMoved
+		}
+
+		return nil, err
+	}
+
+	fixture := &nodeFixture{
+		Network:  *recordNetwork,
+		Recorded: fmt.Sprintf("%s at %s", node.url, time.Now().UTC().Format(time.RFC3339)),
+		Method:   "getunclaimed",
+		Params:   []string{address},
+		Height:   height,
+		Result:   unclaimed,
+	}
+
+	heights := []int64{height}
+
+	for _, balance := range unspents.Balance {
+		if !strings.EqualFold(strings.TrimPrefix(balance.AssetHash, "0x"), strings.TrimPrefix(params.NEOAsset, "0x")) {
+			continue
+		}
+
+		for _, entry := range balance.Unspent {
+			if entry.StartHeight, err = node.startHeight(entry.TxID); err != nil {
+				return nil, err
+			}
+
+			heights = append(heights, entry.StartHeight-1)
+
+			fixture.Unspent = append(fixture.Unspent, entry)
+		}
+	}
+
+	if fixture.SysFee, err = node.sysFee(heights); err != nil {
+		return nil, err
+	}
+
+	return fixture, nil
+}
+
+// TestRecordNodeClaims record the claim responses of -record.node for -record.addresses into
+// testdata/node_claims.json, replacing the recordings of the same method and params
+func TestRecordNodeClaims(t *testing.T) {
+	if *recordNode == "" {
+		t.Skip("set -record.node and -record.addresses to record testdata/node_claims.json")
+	}
+
+	params, ok := GetParams(*recordNetwork)
+	require.True(t, ok, *recordNetwork)
+
+	node := &recordingNode{
+		url:    *recordNode,
+		client: jsonrpc.NewRPCClient(*recordNode),
+		source: NewNodeFeeSource(*recordNode, time.Minute),
+	}
+
+	recorded := make([]*nodeFixture, 0)
+
+	for _, address := range strings.Split(*recordAddresses, ",") {
+		address = strings.TrimSpace(address)
+
+		record := []func() (*nodeFixture, error){
+			func() (*nodeFixture, error) { return node.recordClaimable(address) },
+			func() (*nodeFixture, error) { return node.recordUnclaimed(params, address) },
+		}
+
+		for _, method := range record {
+			for attempt := 1; ; attempt++ {
+				fixture, err := method()
+
+				if err == errHeightMoved && attempt < 10 {
+					continue
+				}
+
+				require.NoError(t, err, address)
+
+				recorded = append(recorded, fixture)
+
+				break
+			}
+		}
+	}
+
+	fixtures := make([]*nodeFixture, 0)
+
+	for _, fixture := range loadNodeFixtures(t, "") {
+		replaced := false
+
+		for _, record := range recorded {
+			replaced = replaced || (record.Method == fixture.Method && strings.Join(record.Params, ",") == strings.Join(fixture.Params, ","))
+		}
+
+		if !replaced {
+			fixtures = append(fixtures, fixture)
+		}
+	}
+
+	data, err := json.MarshalIndent(append(fixtures, recorded...), "", "  ")
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile("testdata/node_claims.json", append(data, '\n'), 0644))
+}
@@ -0,0 +1,128 @@
+package claim
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BlockFeeSource block sys_fee the claim calculation reads, implemented by the insight sys_fee index
+// (postgres), MemoryFeeSource and NodeFeeSource
+type BlockFeeSource interface {
+	// Height the highest block of the source, -1 if the source is empty
+	Height() (int64, error)
+	// SysFeeAmount cumulative sys_fee of blocks up to height (inclusive) in whole GAS, as the node's
+	// GetSysFeeAmount, the amount of height -1 is 0. Only differences are used, a source may count from
+	// another base block and fail the lookups before it
+	SysFeeAmount(height int64) (int64, error)
+}
+
+// MemoryFeeSource BlockFeeSource of preloaded blocks, lookups depending on missing or duplicate
+// blocks fail with *IncompleteError
+type MemoryFeeSource struct {
+	base       int64
+	height     int64
+	amounts    []int64
+	continuity *Continuity
+}
+
+// NewMemoryFeeSource create source of blocks, the blocks need not be sorted
+func NewMemoryFeeSource(blocks []*BlockFee) *MemoryFeeSource {
+
+	source := &MemoryFeeSource{height: -1}
+
+	if len(blocks) == 0 {
+		return source
+	}
+
+	sorted := make([]*BlockFee, len(blocks))
+	copy(sorted, blocks)
+
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Block < sorted[j].Block })
+
+	heights := make([]int64, 0, len(sorted))
+
+	for _, block := range sorted {
+		heights = append(heights, block.Block)
+	}
+
+	source.base = sorted[0].Block
+	source.height = sorted[len(sorted)-1].Block
+	source.continuity = CheckContinuity(heights, source.base, source.height)
+
+	stop := source.continuity.First()
+
+	amount := int64(0)
+
+	for _, block := range sorted {
+		if stop != -1 && block.Block >= stop {
+			break
+		}
+
+		amount += WholeGas(block.SysFee)
+
+		source.amounts = append(source.amounts, amount)
+	}
+
+	return source
+}
+
+// Height the highest loaded block
+func (source *MemoryFeeSource) Height() (int64, error) {
+	return source.height, nil
+}
+
+// SysFeeAmount sys_fee of the loaded blocks up to height, counted from the first loaded block
+func (source *MemoryFeeSource) SysFeeAmount(height int64) (int64, error) {
+
+	if height == source.base-1 {
+		return 0, nil
+	}
+
+	if height < source.base-1 || height > source.height {
+		from, to := source.height+1, height
+
+		if height < source.base {
+			from, to = height+1, source.base-1
+		}
+
+		return 0, &IncompleteError{Continuity: &Continuity{
+			Start: from,
+			End:   to,
+			Gaps:  []*Gap{{From: from, To: to}},
+		}}
+	}
+
+	offset := height - source.base
+
+	if offset >= int64(len(source.amounts)) {
+		return 0, &IncompleteError{Continuity: source.continuity}
+	}
+
+	return source.amounts[offset], nil
+}
+
+// heightSource BlockFeeSource viewed at a lower height
+type heightSource struct {
+	BlockFeeSource
+	height int64
+}
+
+// AtHeight view source at height, unspent utxos accrue through height instead of the source height.
+// Fail if the source is below height
+func AtHeight(source BlockFeeSource, height int64) (BlockFeeSource, error) {
+	current, err := source.Height()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if current < height {
+		return nil, fmt.Errorf("source height %d below %d", current, height)
+	}
+
+	return &heightSource{BlockFeeSource: source, height: height}, nil
+}
+
+func (source *heightSource) Height() (int64, error) {
+	return source.height, nil
+}
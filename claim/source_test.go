@@ -0,0 +1,263 @@
+package claim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+	"github.com/stretchr/testify/require"
+)
+
+// amountSource BlockFeeSource of an amount function
+type amountSource struct {
+	height int64
+	amount func(height int64) (int64, error)
+}
+
+func (source *amountSource) Height() (int64, error) {
+	return source.height, nil
+}
+
+func (source *amountSource) SysFeeAmount(height int64) (int64, error) {
+	if height == -1 {
+		return 0, nil
+	}
+
+	return source.amount(height)
+}
+
+// varyingBlocks blocks [0, end] with sys_fee 0, 0.5, 1 ... 3 GAS and their cumulative amounts
+func varyingBlocks(end int64) ([]*BlockFee, []int64) {
+	blocks := makeBlocks(0, end, 0)
+
+	amounts := make([]int64, len(blocks))
+
+	for i, block := range blocks {
+		block.SysFee = tx.Fixed8(int64(i%7) * 50000000)
+
+		amounts[i] = WholeGas(block.SysFee)
+
+		if i > 0 {
+			amounts[i] += amounts[i-1]
+		}
+	}
+
+	return blocks, amounts
+}
+
+func sourceUTXOs() []*rpc.UTXO {
+	return []*rpc.UTXO{
+		{Vout: rpc.Vout{Value: "10"}, Block: 0, SpentBlock: 100},
+		{Vout: rpc.Vout{Value: "3"}, Block: 17, SpentBlock: 4999},
+		{Vout: rpc.Vout{Value: "1000"}, Block: 1000, SpentBlock: -1},
+		{Vout: rpc.Vout{Value: "1"}, Block: 4999, SpentBlock: -1},
+		{Vout: rpc.Vout{Value: "1"}, Block: 5000, SpentBlock: -1},
+	}
+}
+
+func TestMemoryFeeSource(t *testing.T) {
+	source := NewMemoryFeeSource(append(makeBlocks(13, 20, 100000000), makeBlocks(10, 12, 250000000)...))
+
+	height, err := source.Height()
+	require.NoError(t, err)
+	require.Equal(t, int64(20), height)
+
+	amount, err := source.SysFeeAmount(9)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), amount)
+
+	amount, err = source.SysFeeAmount(12)
+	require.NoError(t, err)
+	require.Equal(t, int64(6), amount)
+
+	amount, err = source.SysFeeAmount(20)
+	require.NoError(t, err)
+	require.Equal(t, int64(14), amount)
+
+	_, err = source.SysFeeAmount(8)
+	require.Equal(t, []*Gap{{From: 9, To: 9}}, err.(*IncompleteError).Continuity.Gaps)
+
+	_, err = source.SysFeeAmount(22)
+	require.Equal(t, []*Gap{{From: 21, To: 22}}, err.(*IncompleteError).Continuity.Gaps)
+
+	source = NewMemoryFeeSource(append(makeBlocks(0, 4, 100000000), makeBlocks(6, 9, 100000000)...))
+
+	amount, err = source.SysFeeAmount(4)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), amount)
+
+	_, err = source.SysFeeAmount(6)
+	require.Equal(t, int64(5), err.(*IncompleteError).Continuity.First())
+
+	height, err = NewMemoryFeeSource(nil).Height()
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), height)
+}
+
+func TestDetailsEndBlock(t *testing.T) {
+	blocks, amounts := varyingBlocks(5000)
+
+	sysFeeAmount := func(height uint32) int64 { return amounts[height] }
+
+	height, details, err := MainNet.Details(sourceUTXOs(), NewMemoryFeeSource(blocks))
+
+	require.NoError(t, err)
+	require.Equal(t, int64(5000), height)
+
+	for _, detail := range details {
+		value := detail.Value
+
+		// spent utxos accrue up to the spending block exclusive, unspent ones through the tip
+		end := detail.UTXO.SpentBlock
+
+		if end == -1 {
+			end = height + 1
+		}
+
+		require.Equal(t, end, detail.End)
+
+		node := nodeCalculateBonus(value, uint32(detail.Start), uint32(end), sysFeeAmount)
+
+		require.Equal(t, node, detail.Gas, "%v", detail.UTXO)
+
+		// the legacy end++ of spent utxos and the legacy tip exclusive end of unspent ones disagree
+		legacy := end + 1
+
+		if detail.UTXO.SpentBlock == -1 {
+			legacy = height
+		}
+
+		if legacy > detail.Start {
+			require.NotEqual(t, nodeCalculateBonus(value, uint32(detail.Start), uint32(legacy), sysFeeAmount), detail.Gas, "%v", detail.UTXO)
+		}
+	}
+}
+
+// TestDetailsEndBlockRecorded the end blocks against recorded node responses, a spent utxo accrues up
+// to its spending block exclusive and an unspent one through the node height (end height + 1)
+func TestDetailsEndBlockRecorded(t *testing.T) {
+	t.Run("spent", func(t *testing.T) {
+		fixtures := loadNodeFixtures(t, "getclaimable")
+
+		require.NotEmpty(t, fixtures, "no recorded getclaimable response in testdata/node_claims.json, %s", recordUsage)
+
+		for _, fixture := range fixtures {
+			params, ok := GetParams(fixture.Network)
+			require.True(t, ok, fixture.Network)
+
+			var result struct {
+				Claimable []*nodeClaimable `json:"claimable"`
+			}
+
+			require.NoError(t, json.Unmarshal(fixture.Result, &result))
+
+			source := recordedSource(fixture)
+
+			for _, entry := range result.Claimable {
+				_, details, err := params.Details([]*rpc.UTXO{claimableUTXO(params, entry)}, source)
+				require.NoError(t, err)
+
+				require.Equal(t, entry.StartHeight, details[0].Start)
+				require.Equal(t, entry.EndHeight, details[0].End)
+				require.Equal(t, parseNodeFixed8(t, entry.Unclaimed), details[0].Gas)
+
+				// the legacy end++ accrues one block more than the node
+				legacy := params.claimGas(details[0].Value, params.getUnClaimedGas(entry.StartHeight, entry.EndHeight+1)+details[0].SysFee)
+
+				require.NotEqual(t, parseNodeFixed8(t, entry.Unclaimed), legacy)
+			}
+		}
+	})
+
+	t.Run("unspent", func(t *testing.T) {
+		fixtures := loadNodeFixtures(t, "getunclaimed")
+
+		require.NotEmpty(t, fixtures, "no recorded getunclaimed response in testdata/node_claims.json, %s", recordUsage)
+
+		for _, fixture := range fixtures {
+			params, ok := GetParams(fixture.Network)
+			require.True(t, ok, fixture.Network)
+
+			var result nodeUnclaimedResult
+
+			require.NoError(t, json.Unmarshal(fixture.Result, &result))
+
+			utxos, source := unspentSource(params, fixture)
+
+			height, details, err := params.Details(utxos, source)
+			require.NoError(t, err)
+			require.Equal(t, fixture.Height, height)
+
+			unavailable := tx.Fixed8(0)
+
+			for _, detail := range details {
+				require.Equal(t, fixture.Height+1, detail.End)
+
+				unavailable += detail.Gas
+			}
+
+			require.Equal(t, parseNodeFixed8(t, result.Unavailable), unavailable, fixture.Recorded)
+		}
+	})
+}
+
+// newTestNode neo node stand-in serving getblockcount and getblocksysfee of amounts
+func newTestNode(t *testing.T, amounts []int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []int64         `json:"params"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response := map[string]interface{}{"jsonrpc": "2.0", "id": request.ID}
+
+		switch request.Method {
+		case "getblockcount":
+			response["result"] = len(amounts)
+		case "getblocksysfee":
+			// neo 2.x returns the amount as string
+			response["result"] = strconv.FormatInt(amounts[request.Params[0]], 10)
+		default:
+			response["error"] = map[string]interface{}{"code": -32601, "message": "Method not found"}
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestNodeFeeSource(t *testing.T) {
+	blocks, amounts := varyingBlocks(5000)
+
+	node := newTestNode(t, amounts)
+	defer node.Close()
+
+	source := NewNodeFeeSource(node.URL, 5*time.Second)
+
+	height, err := source.Height()
+	require.NoError(t, err)
+	require.Equal(t, int64(5000), height)
+
+	amount, err := source.SysFeeAmount(4999)
+	require.NoError(t, err)
+	require.Equal(t, amounts[4999], amount)
+
+	unavailable, available, err := MainNet.CalcUnclaimedGas(sourceUTXOs(), source)
+	require.NoError(t, err)
+
+	expectUnavailable, expectAvailable, err := MainNet.CalcUnclaimedGas(sourceUTXOs(), NewMemoryFeeSource(blocks))
+	require.NoError(t, err)
+
+	require.Equal(t, expectUnavailable, unavailable)
+	require.Equal(t, expectAvailable, available)
+}
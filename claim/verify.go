@@ -0,0 +1,227 @@
+package claim
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+)
+
+// UTXO diff reasons
+const (
+	DiffMissingLocal = "missing_local"
+	DiffMissingNode  = "missing_node"
+	DiffRange        = "range"
+	DiffGas          = "gas"
+)
+
+// ReferenceClaim the reference node's claim of one spent utxo, as getclaimable
+type ReferenceClaim struct {
+	TxID  string
+	N     int
+	Start int64
+	End   int64
+	Gas   tx.Fixed8
+}
+
+// Reference the reference node's unclaimed gas of an address at Height, as getunclaimed and getclaimable.
+// A Partial reference is pinned below the node height, its Unavailable is not known
+type Reference struct {
+	Height      int64
+	Available   tx.Fixed8
+	Unavailable tx.Fixed8
+	Claims      []*ReferenceClaim
+	Partial     bool
+}
+
+// At the reference pinned to height below its own, the claims of the utxos spent after height are dropped
+// and Available is the sum of the rest. The node accrues unspent utxos to its height only, the pinned
+// reference is partial
+func (reference *Reference) At(height int64) *Reference {
+
+	if height >= reference.Height {
+		return reference
+	}
+
+	pinned := &Reference{Height: height, Partial: true}
+
+	for _, claim := range reference.Claims {
+		if claim.End <= height {
+			pinned.Claims = append(pinned.Claims, claim)
+			pinned.Available += claim.Gas
+		}
+	}
+
+	return pinned
+}
+
+// UTXODiff one utxo the local calculation and the reference disagree on
+type UTXODiff struct {
+	UTXO       string `json:"utxo"`
+	Reason     string `json:"reason"`
+	LocalStart int64  `json:"localStart"`
+	LocalEnd   int64  `json:"localEnd"`
+	LocalGas   string `json:"localGas"`
+	NodeStart  int64  `json:"nodeStart"`
+	NodeEnd    int64  `json:"nodeEnd"`
+	NodeGas    string `json:"nodeGas"`
+}
+
+func (diff *UTXODiff) String() string {
+	return fmt.Sprintf(
+		"%s %s local [%d, %d) %s node [%d, %d) %s",
+		diff.UTXO, diff.Reason, diff.LocalStart, diff.LocalEnd, diff.LocalGas, diff.NodeStart, diff.NodeEnd, diff.NodeGas,
+	)
+}
+
+// Verification result of comparing the local claim of an address with the reference
+type Verification struct {
+	Address          string      `json:"address"`
+	Height           int64       `json:"height"`
+	LocalAvailable   string      `json:"localAvailable"`
+	NodeAvailable    string      `json:"nodeAvailable"`
+	LocalUnavailable string      `json:"localUnavailable"`
+	NodeUnavailable  string      `json:"nodeUnavailable"`
+	Diffs            []*UTXODiff `json:"diffs,omitempty"`
+	mismatch         bool
+}
+
+// Match check if the local claim agrees with the reference
+func (verification *Verification) Match() bool {
+	return !verification.mismatch
+}
+
+func (verification *Verification) String() string {
+	return fmt.Sprintf(
+		"%s at %d available %s/%s unavailable %s/%s diffs %d",
+		verification.Address, verification.Height,
+		verification.LocalAvailable, verification.NodeAvailable,
+		verification.LocalUnavailable, verification.NodeUnavailable,
+		len(verification.Diffs),
+	)
+}
+
+// UTXOKey txid:n of utxo, txid lower case without 0x
+func UTXOKey(txid string, n int) string {
+	return fmt.Sprintf("%s:%d", strings.TrimPrefix(strings.ToLower(txid), "0x"), n)
+}
+
+// RewindUTXOs view of the unclaimed utxos at height, utxos created after height are dropped and the
+// ones spent after height are unspent
+func RewindUTXOs(unclaimed []*rpc.UTXO, height int64) []*rpc.UTXO {
+	rewound := make([]*rpc.UTXO, 0, len(unclaimed))
+
+	for _, utxo := range unclaimed {
+		if utxo.Block > height {
+			continue
+		}
+
+		copied := *utxo
+
+		if copied.SpentBlock > height {
+			copied.SpentBlock = -1
+		}
+
+		rewound = append(rewound, &copied)
+	}
+
+	return rewound
+}
+
+func absFixed8(value tx.Fixed8) tx.Fixed8 {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+// Verify compare the claim breakdown of address with the reference, both at reference.Height. Amounts
+// differing by more than tolerance are mismatches, the node reports them as double. The unavailable gas
+// of a partial reference is not compared, its available gas is the sum of the claims, each within tolerance
+func Verify(address string, details []*Detail, reference *Reference, tolerance tx.Fixed8) *Verification {
+
+	unavailable, available := SumDetails(details)
+
+	verification := &Verification{
+		Address:          address,
+		Height:           reference.Height,
+		LocalAvailable:   FormatFixed8(available),
+		NodeAvailable:    FormatFixed8(reference.Available),
+		LocalUnavailable: FormatFixed8(unavailable),
+	}
+
+	if reference.Partial {
+		verification.mismatch = absFixed8(available-reference.Available) > tolerance*tx.Fixed8(len(reference.Claims))
+	} else {
+		verification.NodeUnavailable = FormatFixed8(reference.Unavailable)
+		verification.mismatch = absFixed8(available-reference.Available) > tolerance ||
+			absFixed8(unavailable-reference.Unavailable) > tolerance
+	}
+
+	local := make(map[string]*Detail)
+
+	for _, detail := range details {
+		if detail.Available {
+			local[UTXOKey(detail.UTXO.TransactionID, detail.UTXO.Vout.N)] = detail
+		}
+	}
+
+	for _, ref := range reference.Claims {
+		key := UTXOKey(ref.TxID, ref.N)
+
+		diff := &UTXODiff{
+			UTXO:      key,
+			NodeStart: ref.Start,
+			NodeEnd:   ref.End,
+			NodeGas:   FormatFixed8(ref.Gas),
+		}
+
+		detail, ok := local[key]
+
+		if !ok {
+			diff.Reason = DiffMissingLocal
+			verification.Diffs = append(verification.Diffs, diff)
+			continue
+		}
+
+		delete(local, key)
+
+		diff.LocalStart = detail.Start
+		diff.LocalEnd = detail.End
+		diff.LocalGas = FormatFixed8(detail.Gas)
+
+		if detail.Start != ref.Start || detail.End != ref.End {
+			diff.Reason = DiffRange
+		} else if absFixed8(detail.Gas-ref.Gas) > tolerance {
+			diff.Reason = DiffGas
+		} else {
+			continue
+		}
+
+		verification.Diffs = append(verification.Diffs, diff)
+	}
+
+	for _, detail := range details {
+		key := UTXOKey(detail.UTXO.TransactionID, detail.UTXO.Vout.N)
+
+		if _, ok := local[key]; !ok {
+			continue
+		}
+
+		verification.Diffs = append(verification.Diffs, &UTXODiff{
+			UTXO:       key,
+			Reason:     DiffMissingNode,
+			LocalStart: detail.Start,
+			LocalEnd:   detail.End,
+			LocalGas:   FormatFixed8(detail.Gas),
+		})
+	}
+
+	if len(verification.Diffs) > 0 {
+		verification.mismatch = true
+	}
+
+	return verification
+}
@@ -0,0 +1,129 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewindUTXOs(t *testing.T) {
+	utxos := []*rpc.UTXO{
+		{Block: 10, SpentBlock: 20},
+		{Block: 10, SpentBlock: 40},
+		{Block: 35, SpentBlock: -1},
+	}
+
+	rewound := RewindUTXOs(utxos, 30)
+
+	require.Len(t, rewound, 2)
+	require.Equal(t, int64(20), rewound[0].SpentBlock)
+	require.Equal(t, int64(-1), rewound[1].SpentBlock)
+	require.Equal(t, int64(40), utxos[1].SpentBlock)
+}
+
+func TestVerify(t *testing.T) {
+	blocks, _ := varyingBlocks(5000)
+
+	utxos := sourceUTXOs()
+
+	for i, utxo := range utxos {
+		utxo.TransactionID = "ab"
+		utxo.Vout.N = i
+	}
+
+	source, err := AtHeight(NewMemoryFeeSource(blocks), 4000)
+
+	require.NoError(t, err)
+
+	_, details, err := MainNet.Details(RewindUTXOs(utxos, 4000), source)
+
+	require.NoError(t, err)
+
+	unavailable, available := SumDetails(details)
+
+	reference := &Reference{Height: 4000, Available: available, Unavailable: unavailable}
+
+	for _, detail := range details {
+		if detail.Available {
+			reference.Claims = append(reference.Claims, &ReferenceClaim{
+				TxID:  "0xAB",
+				N:     detail.UTXO.Vout.N,
+				Start: detail.Start,
+				End:   detail.End,
+				Gas:   detail.Gas,
+			})
+		}
+	}
+
+	require.Len(t, reference.Claims, 1)
+
+	verification := Verify("address", details, reference, 0)
+
+	require.True(t, verification.Match(), "%s", verification)
+	require.Empty(t, verification.Diffs)
+
+	// off by one end block and a satoshi of gas
+	reference.Claims[0].End++
+	reference.Unavailable++
+
+	verification = Verify("address", details, reference, 0)
+
+	require.False(t, verification.Match())
+	require.Equal(t, DiffRange, verification.Diffs[0].Reason)
+
+	reference.Claims[0].End--
+
+	require.True(t, Verify("address", details, reference, 1).Match())
+
+	reference.Claims[0].Gas -= 2
+
+	verification = Verify("address", details, reference, 1)
+
+	require.False(t, verification.Match())
+	require.Equal(t, DiffGas, verification.Diffs[0].Reason)
+
+	reference.Claims = []*ReferenceClaim{{TxID: "cd", N: 0, Start: 1, End: 2, Gas: tx.Fixed8(1)}}
+
+	verification = Verify("address", details, reference, 1)
+
+	require.Len(t, verification.Diffs, 2)
+	require.Equal(t, DiffMissingLocal, verification.Diffs[0].Reason)
+	require.Equal(t, DiffMissingNode, verification.Diffs[1].Reason)
+	require.Equal(t, "ab:0", verification.Diffs[1].UTXO)
+
+	// pinned below the node height, the claims spent after it are unspent locally
+	reference = &Reference{
+		Height:      4000,
+		Available:   tx.Fixed8(30),
+		Unavailable: tx.Fixed8(1000),
+		Claims: []*ReferenceClaim{
+			{TxID: "ab", N: 0, Start: 0, End: 100, Gas: details[0].Gas},
+			{TxID: "ab", N: 1, Start: 17, End: 3999, Gas: tx.Fixed8(20)},
+		},
+	}
+
+	source, err = AtHeight(NewMemoryFeeSource(blocks), 3000)
+	require.NoError(t, err)
+
+	_, details, err = MainNet.Details(RewindUTXOs(utxos, 3000), source)
+	require.NoError(t, err)
+
+	pinned := reference.At(3000)
+
+	require.True(t, pinned.Partial)
+	require.Equal(t, int64(3000), pinned.Height)
+	require.Len(t, pinned.Claims, 1)
+	require.Equal(t, details[0].Gas, pinned.Available)
+	require.Equal(t, reference, reference.At(4000))
+
+	verification = Verify("address", details, pinned, 1)
+
+	require.True(t, verification.Match(), "%s", verification)
+	require.Empty(t, verification.NodeUnavailable)
+
+	_, err = AtHeight(NewMemoryFeeSource(blocks), 5001)
+
+	require.Error(t, err)
+}
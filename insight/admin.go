@@ -0,0 +1,38 @@
+package insight
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+)
+
+// runAdmin serve the metrics on insight.admin.listen, never on the public listener. Off unless
+// the address is set, never return then
+func (server *Server) runAdmin() {
+	listen := server.cnf.GetString("insight.admin.listen", "")
+
+	if listen == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(server.cnf.GetString("insight.metrics", "/debug/vars"), serveMetrics)
+
+	logger.InfoF("serve admin on %s", listen)
+
+	logger.Fatal(http.ListenAndServe(listen, mux))
+}
+
+// serveMetrics the expvar vars except cmdline, the command line may carry config paths and credentials
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	vars := make(map[string]json.RawMessage)
+
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key != "cmdline" {
+			vars[kv.Key] = json.RawMessage(kv.Value.String())
+		}
+	})
+
+	writeJSON(w, vars)
+}
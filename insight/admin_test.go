@@ -0,0 +1,23 @@
+package insight
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeMetrics(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	serveMetrics(w, httptest.NewRequest("GET", "/debug/vars", nil))
+
+	var vars map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &vars))
+	require.NotNil(t, vars["claim_sync_workers"])
+	require.NotNil(t, vars["memstats"])
+	_, ok := vars["cmdline"]
+	require.False(t, ok)
+}
@@ -0,0 +1,115 @@
+package insight
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// isBatch check if the request body is a jsonrpc batch (json array)
+func isBatch(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+
+	return len(data) > 0 && data[0] == '['
+}
+
+// decodeBatch split jsonrpc batch into raw request messages, if the batch itself is
+// malformed, empty or too large, return single error response as the spec requires
+func (server *Server) decodeBatch(data []byte) ([]json.RawMessage, *RPCResponse) {
+
+	var messages []json.RawMessage
+
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, newRPCError(nil, JSONRPCParserError, "parse error", nil)
+	}
+
+	if len(messages) == 0 {
+		return nil, newRPCError(nil, JSONRPCInvalidRequest, "invalid request: empty batch", nil)
+	}
+
+	if server.batchLimit > 0 && len(messages) > server.batchLimit {
+		return nil, newRPCError(
+			nil, JSONRPCInvalidRequest,
+			fmt.Sprintf("invalid request: batch size %d exceeds limit %d", len(messages), server.batchLimit),
+			nil,
+		)
+	}
+
+	return messages, nil
+}
+
+// dispatchBatch dispatch jsonrpc 2.0 batch request concurrently, the responses keep
+// the order of requests and notifications produce no response entry
+func (server *Server) dispatchBatch(data []byte) ([]*RPCResponse, *RPCResponse) {
+
+	messages, rpcerr := server.decodeBatch(data)
+
+	if rpcerr != nil {
+		return nil, rpcerr
+	}
+
+	requests := make([]*RPCRequest, len(messages))
+	responses := make([]*RPCResponse, len(messages))
+
+	for i, message := range messages {
+
+		request, err := makeRPCRequest(message)
+
+		if err != nil {
+			responses[i] = newRPCError(nil, JSONRPCInvalidRequest, "invalid request", nil)
+			continue
+		}
+
+		requests[i] = request
+	}
+
+	server.callAll(requests, responses)
+
+	result := make([]*RPCResponse, 0, len(responses))
+
+	for _, response := range responses {
+		if response != nil {
+			result = append(result, response)
+		}
+	}
+
+	return result, nil
+}
+
+// callAll call the non nil requests concurrently within the batch concurrency limit,
+// the response of requests[i] is stored in responses[i]
+func (server *Server) callAll(requests []*RPCRequest, responses []*RPCResponse) {
+
+	workers := server.batchWorkers
+
+	if workers <= 0 {
+		workers = 1
+	}
+
+	semaphore := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+
+	for i, request := range requests {
+
+		if request == nil {
+			continue
+		}
+
+		wg.Add(1)
+
+		semaphore <- struct{}{}
+
+		go func(i int, request *RPCRequest) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			responses[i] = server.call(request)
+		}(i, request)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,140 @@
+package insight
+
+import (
+	"encoding/json"
+	"expvar"
+	"time"
+
+	"github.com/dynamicgo/config"
+	"github.com/go-redis/redis"
+	"github.com/go-xorm/xorm"
+	"github.com/inwecrypto/neo-insight/claim"
+)
+
+// claim checkpoint metrics, published by expvar
+var (
+	checkpointHits   = expvar.NewInt("claim_checkpoint_hits")
+	checkpointMisses = expvar.NewInt("claim_checkpoint_misses")
+)
+
+// checkpointStore per address claim checkpoints in redis
+type checkpointStore struct {
+	redisclient *redis.Client
+	prefix      string
+	ttl         time.Duration
+}
+
+func newCheckpointStore(cnf *config.Config, client *redis.Client) *checkpointStore {
+	return &checkpointStore{
+		redisclient: client,
+		prefix:      cnf.GetString("insight.checkpoint.prefix", "insight:checkpoint"),
+		ttl:         time.Second * cnf.GetDuration("insight.checkpoint.ttl", 7*24*3600),
+	}
+}
+
+func (store *checkpointStore) key(address string) string {
+	return store.prefix + ":" + address
+}
+
+// load the checkpoint of address, nil if none
+func (store *checkpointStore) load(address string) (*claim.Checkpoint, error) {
+	data, err := store.redisclient.Get(store.key(address)).Bytes()
+
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &claim.Checkpoint{}
+
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+func (store *checkpointStore) save(address string, checkpoint *claim.Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+
+	if err != nil {
+		return err
+	}
+
+	return store.redisclient.Set(store.key(address), data, store.ttl).Err()
+}
+
+// utxoFingerprint digest of the unclaimed utxo set of address at tip computed by postgres, it changes
+// when an utxo is created, spent or claimed through tip. The later blocks are not indexed yet, their
+// utxos are left out and their spends are unspent at tip like claim.RewindUTXOs
+func utxoFingerprint(engine *xorm.Engine, address, asset string, tip int64) (string, error) {
+	rows, err := engine.QueryString(
+		`select coalesce(md5(string_agg(
+			tx || ':' || n || ':' || create_block || ':' || (case when spent_block > ? then -1 else spent_block end),
+			',' order by tx, n
+		)), '') as fingerprint
+		from neo_utxo where address = ? and asset = ? and claimed = FALSE and create_block <= ?`,
+		tip, address, asset, tip,
+	)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(rows) == 0 {
+		return "", nil
+	}
+
+	return rows[0]["fingerprint"], nil
+}
+
+// extendCheckpoint the claim breakdown of address at tip from its checkpoint, nil if the checkpoint
+// is missing or the unclaimed utxo set changed since
+func (server *Server) extendCheckpoint(address, fingerprint string, tip int64) []*claim.Detail {
+
+	if server.checkpoints == nil {
+		return nil
+	}
+
+	checkpoint, err := server.checkpoints.load(address)
+
+	if err != nil {
+		logger.ErrorF("load claim checkpoint of %s err, %s", address, err)
+		return nil
+	}
+
+	if checkpoint == nil || checkpoint.Fingerprint != fingerprint || checkpoint.Height > tip {
+		checkpointMisses.Add(1)
+		return nil
+	}
+
+	from := checkpoint.Height
+
+	details, err := server.network.Extend(checkpoint, server.sysfee, tip)
+
+	if err != nil {
+		logger.ErrorF("extend claim checkpoint of %s from %d to %d err, %s", address, from, tip, err)
+		return nil
+	}
+
+	checkpointHits.Add(1)
+
+	server.saveCheckpoint(address, checkpoint)
+
+	logger.DebugF("extend claim checkpoint of %s from %d to %d", address, from, tip)
+
+	return details
+}
+
+func (server *Server) saveCheckpoint(address string, checkpoint *claim.Checkpoint) {
+	if server.checkpoints == nil {
+		return
+	}
+
+	if err := server.checkpoints.save(address, checkpoint); err != nil {
+		logger.ErrorF("save claim checkpoint of %s err, %s", address, err)
+	}
+}
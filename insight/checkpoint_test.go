@@ -0,0 +1,61 @@
+package insight
+
+import (
+	"testing"
+
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetailsAtTip(t *testing.T) {
+	store := &memorySysFeeStore{fees: make(map[int64]*SysFee)}
+
+	for block := int64(0); block <= 10; block++ {
+		store.fees[block] = &SysFee{Block: block, Amount: block * 2}
+	}
+
+	server := &Server{network: claim.MainNet, sysfee: newTestSysFeeIndex(store)}
+	server.sysfee.height = 10
+
+	// neo_utxo is ahead of the index at 10
+	utxos := []*rpc.UTXO{
+		{TransactionID: "01", Vout: rpc.Vout{Value: "100"}, Block: 2, SpentBlock: 6},
+		{TransactionID: "02", Vout: rpc.Vout{Value: "100"}, Block: 4, SpentBlock: 12},
+		{TransactionID: "03", Vout: rpc.Vout{Value: "100"}, Block: 13, SpentBlock: -1},
+	}
+
+	details, err := server.detailsAt(utxos, 10)
+	require.NoError(t, err)
+	require.Len(t, details, 2)
+
+	require.True(t, details[0].Available)
+	require.Equal(t, int64(6), details[0].End)
+
+	// spent after the tip, unspent at the tip
+	require.False(t, details[1].Available)
+	require.Equal(t, int64(11), details[1].End)
+
+	// the checkpoint of the tip view extends like the utxos computed at the new tip
+	for block := int64(11); block <= 20; block++ {
+		store.fees[block] = &SysFee{Block: block, Amount: block * 2}
+	}
+
+	server.sysfee.height = 20
+
+	checkpoint := claim.NewCheckpoint(10, "fingerprint", details)
+
+	extended, err := server.network.Extend(checkpoint, server.sysfee, 11)
+	require.NoError(t, err)
+
+	expect, err := server.detailsAt(claim.RewindUTXOs(utxos, 11), 11)
+	require.NoError(t, err)
+	require.Len(t, extended, 2)
+	require.Equal(t, expect[1].Gas, extended[1].Gas)
+
+	// the index is empty
+	server.sysfee.height = -1
+
+	_, _, err = server.claimDetails("a")
+	require.Error(t, err)
+}
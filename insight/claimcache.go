@@ -0,0 +1,302 @@
+package insight
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dynamicgo/config"
+	"github.com/go-redis/redis"
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+)
+
+// claimCacheVersion cache key version, bump it when CachedClaim format changes
+const claimCacheVersion = "v2"
+
+// CachedClaim cached unclaimed gas of an address computed at Height on Computed. Weight is the sum of value /
+// TotalNEO of the unspent utxos, until a block touches the address its unavailable gas only grows by
+// Weight for each GAS of all NEO the new blocks accrue
+type CachedClaim struct {
+	Unclaimed *rpc.Unclaimed `json:"unclaimed"`
+	Height    int64          `json:"height"`
+	Weight    int64          `json:"weight"`
+	Computed  time.Time      `json:"computed"`
+}
+
+// at the cached claim brought to tip from the height delta
+func (cached *CachedClaim) at(network *claim.Params, source claim.BlockFeeSource, tip int64) (*rpc.Unclaimed, error) {
+
+	if tip <= cached.Height || cached.Weight == 0 {
+		return cached.Unclaimed, nil
+	}
+
+	accrued, err := network.Accrue(source, cached.Height, tip)
+
+	if err != nil {
+		return nil, err
+	}
+
+	unavailable, err := claim.ParseFixed8(cached.Unclaimed.Unavailable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	unclaimed := *cached.Unclaimed
+
+	unclaimed.Unavailable = claim.FormatFixed8(unavailable + tx.Fixed8(cached.Weight*accrued))
+
+	return &unclaimed, nil
+}
+
+// ClaimCache cache of the computed claims of addresses
+type ClaimCache interface {
+	// Get the cached claim of address, nil if none
+	Get(address string) (*CachedClaim, error)
+	// Set cache the claim of address
+	Set(address string, claim *CachedClaim) error
+	// Cached the addresses with a cached claim
+	Cached(addresses []string) ([]string, error)
+}
+
+// redisDefault true if the claim cache is in redis, the other redis stores (sync queue, checkpoints
+// and proxy cache) default to redis along with it and to memory or off otherwise, insight runs
+// without redis then
+func redisDefault(cnf *config.Config) bool {
+	return cnf.GetString("insight.claim_cache.backend", "redis") == "redis"
+}
+
+// newClaimCache create the cache of insight.claim_cache.backend, compute is the claim calculation the
+// passthrough cache calls
+func newClaimCache(cnf *config.Config, client func() *redis.Client, compute func(address string) (*CachedClaim, error)) (ClaimCache, error) {
+
+	ttl := time.Second * cnf.GetDuration("insight.claim_cache.ttl", 24*3600)
+
+	switch backend := cnf.GetString("insight.claim_cache.backend", "redis"); backend {
+	case "redis":
+		return newRedisClaimCache(client(), cnf.GetString("insight.claim_cache.prefix", "insight:claim"), ttl), nil
+	case "lru":
+		return newLRUClaimCache(int(cnf.GetInt64("insight.claim_cache.size", 10000)), ttl), nil
+	case "passthrough":
+		return newPassthroughClaimCache(compute), nil
+	default:
+		return nil, fmt.Errorf("unknown insight.claim_cache.backend %s", backend)
+	}
+}
+
+// redisClaimCache ClaimCache of redis keys prefix:version:address
+type redisClaimCache struct {
+	redisclient *redis.Client
+	prefix      string
+	ttl         time.Duration
+}
+
+func newRedisClaimCache(client *redis.Client, prefix string, ttl time.Duration) *redisClaimCache {
+	return &redisClaimCache{
+		redisclient: client,
+		prefix:      prefix,
+		ttl:         ttl,
+	}
+}
+
+func (cache *redisClaimCache) key(address string) string {
+	return fmt.Sprintf("%s:%s:%s", cache.prefix, claimCacheVersion, address)
+}
+
+func (cache *redisClaimCache) Get(address string) (*CachedClaim, error) {
+	data, err := cache.redisclient.Get(cache.key(address)).Bytes()
+
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	claim := &CachedClaim{}
+
+	if err := json.Unmarshal(data, claim); err != nil {
+		return nil, err
+	}
+
+	return claim, nil
+}
+
+func (cache *redisClaimCache) Set(address string, claim *CachedClaim) error {
+	data, err := json.Marshal(claim)
+
+	if err != nil {
+		return err
+	}
+
+	return cache.redisclient.Set(cache.key(address), data, cache.ttl).Err()
+}
+
+func (cache *redisClaimCache) Cached(addresses []string) ([]string, error) {
+
+	pipe := cache.redisclient.Pipeline()
+
+	defer pipe.Close()
+
+	exists := make([]*redis.IntCmd, 0, len(addresses))
+
+	for _, address := range addresses {
+		exists = append(exists, pipe.Exists(cache.key(address)))
+	}
+
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+
+	cached := make([]string, 0)
+
+	for i, cmd := range exists {
+		if cmd.Val() > 0 {
+			cached = append(cached, addresses[i])
+		}
+	}
+
+	return cached, nil
+}
+
+type lruEntry struct {
+	address string
+	data    []byte
+	expire  time.Time
+}
+
+// lruClaimCache in process ClaimCache of the size most recently used claims, the claims are stored
+// encoded as in redis so callers never share them
+type lruClaimCache struct {
+	mutex   sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newLRUClaimCache(size int, ttl time.Duration) *lruClaimCache {
+	if size < 1 {
+		size = 1
+	}
+
+	return &lruClaimCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// entry the unexpired entry of address, must be called with mutex held
+func (cache *lruClaimCache) entry(address string) *lruEntry {
+	element, ok := cache.entries[address]
+
+	if !ok {
+		return nil
+	}
+
+	entry := element.Value.(*lruEntry)
+
+	if time.Now().After(entry.expire) {
+		cache.order.Remove(element)
+		delete(cache.entries, address)
+		return nil
+	}
+
+	return entry
+}
+
+func (cache *lruClaimCache) Get(address string) (*CachedClaim, error) {
+	cache.mutex.Lock()
+
+	entry := cache.entry(address)
+
+	if entry != nil {
+		cache.order.MoveToFront(cache.entries[address])
+	}
+
+	cache.mutex.Unlock()
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	claim := &CachedClaim{}
+
+	if err := json.Unmarshal(entry.data, claim); err != nil {
+		return nil, err
+	}
+
+	return claim, nil
+}
+
+func (cache *lruClaimCache) Set(address string, claim *CachedClaim) error {
+	data, err := json.Marshal(claim)
+
+	if err != nil {
+		return err
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry := &lruEntry{address: address, data: data, expire: time.Now().Add(cache.ttl)}
+
+	if element, ok := cache.entries[address]; ok {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return nil
+	}
+
+	cache.entries[address] = cache.order.PushFront(entry)
+
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*lruEntry).address)
+	}
+
+	return nil
+}
+
+func (cache *lruClaimCache) Cached(addresses []string) ([]string, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cached := make([]string, 0)
+
+	for _, address := range addresses {
+		if cache.entry(address) != nil {
+			cached = append(cached, address)
+		}
+	}
+
+	return cached, nil
+}
+
+// passthroughClaimCache ClaimCache caching nothing, every Get computes the claim
+type passthroughClaimCache struct {
+	compute func(address string) (*CachedClaim, error)
+}
+
+func newPassthroughClaimCache(compute func(address string) (*CachedClaim, error)) *passthroughClaimCache {
+	return &passthroughClaimCache{compute: compute}
+}
+
+func (cache *passthroughClaimCache) Get(address string) (*CachedClaim, error) {
+	return cache.compute(address)
+}
+
+func (cache *passthroughClaimCache) Set(address string, claim *CachedClaim) error {
+	return nil
+}
+
+func (cache *passthroughClaimCache) Cached(addresses []string) ([]string, error) {
+	return []string{}, nil
+}
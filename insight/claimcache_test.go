@@ -0,0 +1,282 @@
+package insight
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dynamicgo/config"
+	"github.com/go-redis/redis"
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func testCachedClaim(height int64) *CachedClaim {
+	return &CachedClaim{
+		Unclaimed: &rpc.Unclaimed{Available: "1.5", Unavailable: "0.25"},
+		Height:    height,
+		Weight:    2,
+	}
+}
+
+// testClaimCache conformance of the ClaimCache backends. put writes the claim where the cache gets it
+// from, Set for the caching backends and the computed claims for passthrough, which caches nothing
+func testClaimCache(t *testing.T, cache ClaimCache, put func(address string, claim *CachedClaim) error, caches bool) {
+	cached, err := cache.Get("AUnknown")
+	require.NoError(t, err)
+	require.Nil(t, cached)
+
+	require.NoError(t, put("AFirst", testCachedClaim(100)))
+	require.NoError(t, put("ASecond", testCachedClaim(200)))
+
+	cached, err = cache.Get("AFirst")
+	require.NoError(t, err)
+	require.Equal(t, testCachedClaim(100), cached)
+
+	// callers never share the cached claim
+	cached.Unclaimed.Available = "0"
+	cached.Height = 0
+
+	cached, err = cache.Get("AFirst")
+	require.NoError(t, err)
+	require.Equal(t, testCachedClaim(100), cached)
+
+	require.NoError(t, put("AFirst", testCachedClaim(101)))
+
+	cached, err = cache.Get("AFirst")
+	require.NoError(t, err)
+	require.Equal(t, int64(101), cached.Height)
+
+	addresses, err := cache.Cached([]string{"AUnknown", "ASecond", "AFirst"})
+	require.NoError(t, err)
+
+	if caches {
+		require.Equal(t, []string{"ASecond", "AFirst"}, addresses)
+	} else {
+		require.Empty(t, addresses)
+	}
+
+	addresses, err = cache.Cached(nil)
+	require.NoError(t, err)
+	require.Empty(t, addresses)
+}
+
+func TestLRUClaimCache(t *testing.T) {
+	lru := newLRUClaimCache(10, time.Minute)
+
+	testClaimCache(t, lru, lru.Set, true)
+
+	cache := newLRUClaimCache(2, time.Minute)
+
+	require.NoError(t, cache.Set("a", testCachedClaim(1)))
+	require.NoError(t, cache.Set("b", testCachedClaim(2)))
+
+	// a is used more recently than b, c evicts b
+	_, err := cache.Get("a")
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Set("c", testCachedClaim(3)))
+
+	addresses, err := cache.Cached([]string{"a", "b", "c"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "c"}, addresses)
+
+	cache = newLRUClaimCache(2, time.Millisecond)
+
+	require.NoError(t, cache.Set("a", testCachedClaim(1)))
+
+	time.Sleep(5 * time.Millisecond)
+
+	cached, err := cache.Get("a")
+	require.NoError(t, err)
+	require.Nil(t, cached)
+	require.Equal(t, 0, cache.order.Len())
+}
+
+func TestRedisClaimCache(t *testing.T) {
+	client, stop := newTestRedis(t)
+	defer stop()
+
+	cache := newRedisClaimCache(client, "insight:test:claim", time.Minute)
+
+	testClaimCache(t, cache, cache.Set, true)
+
+	// keys are namespaced and versioned
+	exists, err := client.Exists("insight:test:claim:" + claimCacheVersion + ":AFirst").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), exists)
+}
+
+// newTestRedis redis stand-in serving get, set, exists and del over RESP, enough for the redis backed
+// claim cache
+func newTestRedis(t *testing.T) (*redis.Client, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var mutex sync.Mutex
+
+	values := make(map[string]string)
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+
+		for {
+			args, err := readRESP(reader)
+
+			if err != nil {
+				return
+			}
+
+			mutex.Lock()
+
+			switch strings.ToLower(args[0]) {
+			case "get":
+				if value, ok := values[args[1]]; ok {
+					fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+				} else {
+					fmt.Fprint(conn, "$-1\r\n")
+				}
+			case "set":
+				values[args[1]] = args[2]
+				fmt.Fprint(conn, "+OK\r\n")
+			case "exists", "del":
+				count := 0
+
+				for _, key := range args[1:] {
+					if _, ok := values[key]; ok {
+						count++
+
+						if strings.ToLower(args[0]) == "del" {
+							delete(values, key)
+						}
+					}
+				}
+
+				fmt.Fprintf(conn, ":%d\r\n", count)
+			default:
+				fmt.Fprintf(conn, "-ERR unknown command %s\r\n", args[0])
+			}
+
+			mutex.Unlock()
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			go serve(conn)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String()})
+
+	return client, func() {
+		client.Close()
+		listener.Close()
+	}
+}
+
+// readRESP read one command, an array of bulk strings
+func readRESP(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+
+	args := make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, size+2)
+
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+
+		args = append(args, string(data[:size]))
+	}
+
+	return args, nil
+}
+
+func TestPassthroughClaimCache(t *testing.T) {
+	computed := make(map[string]*CachedClaim)
+
+	cache := newPassthroughClaimCache(func(address string) (*CachedClaim, error) {
+		claim, ok := computed[address]
+
+		if !ok {
+			return nil, nil
+		}
+
+		copied := *claim
+		unclaimed := *claim.Unclaimed
+		copied.Unclaimed = &unclaimed
+
+		return &copied, nil
+	})
+
+	testClaimCache(t, cache, func(address string, claim *CachedClaim) error {
+		computed[address] = claim
+		return nil
+	}, false)
+
+	// Set caches nothing, Get computes again
+	require.NoError(t, cache.Set("AFirst", testCachedClaim(300)))
+
+	cached, err := cache.Get("AFirst")
+	require.NoError(t, err)
+	require.Equal(t, int64(101), cached.Height)
+}
+
+func TestNewClaimCache(t *testing.T) {
+	cnf, err := config.New([]byte(`{"insight": {"claim_cache": {"backend": "lru", "size": 5}}}`))
+	require.NoError(t, err)
+
+	cache, err := newClaimCache(cnf, nil, nil)
+	require.NoError(t, err)
+	require.IsType(t, &lruClaimCache{}, cache)
+	require.Equal(t, 5, cache.(*lruClaimCache).size)
+
+	cnf, err = config.New([]byte(`{"insight": {"claim_cache": {"backend": "passthrough"}}}`))
+	require.NoError(t, err)
+
+	cache, err = newClaimCache(cnf, nil, nil)
+	require.NoError(t, err)
+	require.IsType(t, &passthroughClaimCache{}, cache)
+
+	cnf, err = config.New([]byte(`{"insight": {"claim_cache": {"backend": "memcached"}}}`))
+	require.NoError(t, err)
+
+	_, err = newClaimCache(cnf, nil, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package insight
+
+import (
+	"github.com/inwecrypto/neo-insight/claim"
+)
+
+// ClaimDetail unclaimed gas of address with the breakdown of each utxo, the totals are the sum of
+// the utxos' gas, the same as claim method computes
+type ClaimDetail struct {
+	Address     string             `json:"address"`
+	Height      int64              `json:"height" desc:"index tip unspent utxos accrue through"`
+	Available   string             `json:"available"`
+	Unavailable string             `json:"unavailable"`
+	UTXOs       []*UTXOClaimDetail `json:"utxos"`
+}
+
+// UTXOClaimDetail claim breakdown of one unclaimed NEO utxo, generated and sysfee are the GAS of all
+// NEO in blocks [start, end), generatedGas + sysfeeGas == gas
+type UTXOClaimDetail struct {
+	TransactionID string `json:"txid"`
+	N             int    `json:"n"`
+	Value         string `json:"value"`
+	Start         int64  `json:"start"`
+	End           int64  `json:"end"`
+	Generated     int64  `json:"generated"`
+	SysFee        int64  `json:"sysfee"`
+	GeneratedGas  string `json:"generatedGas"`
+	SysFeeGas     string `json:"sysfeeGas"`
+	Gas           string `json:"gas"`
+	Available     bool   `json:"available"`
+}
+
+func (server *Server) getClaimDetail(params interface{}) (interface{}, *JSONRPCError) {
+	address, rpcerr := normalizeAddress(params.(*claimParams).Address)
+
+	if rpcerr != nil {
+		return nil, rpcerr
+	}
+
+	tip, details, err := server.claimDetails(address)
+
+	if err != nil {
+		return nil, asJSONRPCError(err)
+	}
+
+	return newClaimDetail(address, tip, details), nil
+}
+
+func newClaimDetail(address string, tip int64, details []*claim.Detail) *ClaimDetail {
+	unavailable, available := claim.SumDetails(details)
+
+	result := &ClaimDetail{
+		Address:     address,
+		Height:      tip,
+		Available:   claim.FormatFixed8(available),
+		Unavailable: claim.FormatFixed8(unavailable),
+		UTXOs:       make([]*UTXOClaimDetail, 0, len(details)),
+	}
+
+	for _, detail := range details {
+		result.UTXOs = append(result.UTXOs, &UTXOClaimDetail{
+			TransactionID: detail.UTXO.TransactionID,
+			N:             detail.UTXO.Vout.N,
+			Value:         claim.FormatFixed8(detail.Value),
+			Start:         detail.Start,
+			End:           detail.End,
+			Generated:     detail.Generated,
+			SysFee:        detail.SysFee,
+			GeneratedGas:  claim.FormatFixed8(detail.GeneratedGas),
+			SysFeeGas:     claim.FormatFixed8(detail.SysFeeGas),
+			Gas:           claim.FormatFixed8(detail.Gas),
+			Available:     detail.Available,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,201 @@
+package insight
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/inwecrypto/neogo/rpc"
+)
+
+// claim result status
+const (
+	// ClaimFresh the claim is accrued to the index tip and no block touched the address since it was computed
+	ClaimFresh = "fresh"
+	// ClaimStale a refresh of the claim is pending, a block touched the address or the accrual failed
+	ClaimStale = "stale"
+	// ClaimPending the claim is not computed yet, retry later
+	ClaimPending = "pending"
+)
+
+// claim result metrics, published by expvar
+var (
+	claimFresh   = expvar.NewInt("claim_status_fresh")
+	claimStale   = expvar.NewInt("claim_status_stale")
+	claimPending = expvar.NewInt("claim_status_pending")
+
+	claimFallbackComputed  = expvar.NewInt("claim_fallback_computed")
+	claimFallbackShared    = expvar.NewInt("claim_fallback_shared")
+	claimFallbackSaturated = expvar.NewInt("claim_fallback_saturated")
+)
+
+// ClaimResult cached unclaimed gas of an address with its freshness, a pending result has no gas. The
+// gas fields keep the names of rpc.Unclaimed
+type ClaimResult struct {
+	Available   string      `json:"Available,omitempty"`
+	Unavailable string      `json:"Unavailable,omitempty" desc:"accrued to the index tip"`
+	Claims      []*rpc.UTXO `json:"Claims"`
+	Height      int64       `json:"height" desc:"block height the claim was computed at, -1 if pending"`
+	Computed    *time.Time  `json:"computed,omitempty" desc:"time the claim was computed"`
+	Status      string      `json:"status" desc:"fresh, stale or pending"`
+}
+
+func pendingClaim() *ClaimResult {
+	claimPending.Add(1)
+
+	return &ClaimResult{Height: -1, Status: ClaimPending}
+}
+
+// claimResult the cached claim of address. On a miss it is computed within insight.claim.sync_deadline
+// if set, else or if the deadline passes it is pending and the sync workers compute it
+func (server *Server) claimResult(address string) *ClaimResult {
+
+	cached, err := server.claimCache.Get(address)
+
+	if err != nil {
+		logger.ErrorF("get cached claim for address %s err, %s", address, err)
+		cached = nil
+	}
+
+	if cached == nil || cached.Unclaimed == nil {
+		if cached = server.claimFallback(address, server.doGetClaim); cached == nil {
+			return pendingClaim()
+		}
+	}
+
+	status := ClaimFresh
+
+	unclaimed, err := cached.at(server.network, server.sysfee, server.sysfee.Indexed())
+
+	if err != nil {
+		logger.ErrorF("accrue cached claim for address %s from %d err, %s", address, cached.Height, err)
+		unclaimed = cached.Unclaimed
+		status = ClaimStale
+	}
+
+	if pending, err := server.syncStore.pending(address); err != nil {
+		logger.ErrorF("check pending refresh of address %s err, %s", address, err)
+	} else if pending {
+		status = ClaimStale
+	}
+
+	if status == ClaimFresh {
+		claimFresh.Add(1)
+	} else {
+		claimStale.Add(1)
+	}
+
+	computed := cached.Computed
+
+	return &ClaimResult{
+		Available:   unclaimed.Available,
+		Unavailable: unclaimed.Unavailable,
+		Claims:      unclaimed.Claims,
+		Height:      cached.Height,
+		Computed:    &computed,
+		Status:      status,
+	}
+}
+
+// claimFlight one fallback claim computation, done is closed when it returns, cached is nil if it failed
+type claimFlight struct {
+	done   chan struct{}
+	cached *CachedClaim
+}
+
+// claimFlights the fallback claim computations in flight, one per address and at most
+// insight.claim.sync_concurrency at once
+type claimFlights struct {
+	mutex   sync.Mutex
+	flights map[string]*claimFlight
+	slots   chan struct{}
+}
+
+func newClaimFlights(concurrency int) *claimFlights {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &claimFlights{
+		flights: make(map[string]*claimFlight),
+		slots:   make(chan struct{}, concurrency),
+	}
+}
+
+// start the flight of address or join the one in flight, nil if all slots are taken
+func (flights *claimFlights) start(address string, compute func() *CachedClaim) *claimFlight {
+	flights.mutex.Lock()
+	defer flights.mutex.Unlock()
+
+	if flight, ok := flights.flights[address]; ok {
+		claimFallbackShared.Add(1)
+		return flight
+	}
+
+	select {
+	case flights.slots <- struct{}{}:
+	default:
+		claimFallbackSaturated.Add(1)
+		return nil
+	}
+
+	flight := &claimFlight{done: make(chan struct{})}
+
+	flights.flights[address] = flight
+
+	claimFallbackComputed.Add(1)
+
+	go func() {
+		flight.cached = compute()
+
+		flights.mutex.Lock()
+		delete(flights.flights, address)
+		flights.mutex.Unlock()
+
+		<-flights.slots
+
+		close(flight.done)
+	}()
+
+	return flight
+}
+
+// claimFallback compute the claim of address missing in the cache within insight.claim.sync_deadline,
+// nil if the deadline is unset or passes, or the compute fails. Requests for the same address share one
+// computation, which is cached when it returns even after the deadline. If the deadline is unset or all
+// insight.claim.sync_concurrency computations are taken the sync workers compute it
+func (server *Server) claimFallback(address string, compute func(address string) (*CachedClaim, error)) *CachedClaim {
+
+	if server.claimDeadline <= 0 {
+		server.scheduleSync(address)
+		return nil
+	}
+
+	flight := server.claimFlights.start(address, func() *CachedClaim {
+		cached, err := compute(address)
+
+		if err != nil {
+			logger.DebugF("compute claim for address %s err, %s", address, err)
+			server.scheduleSync(address)
+			return nil
+		}
+
+		if err := server.claimCache.Set(address, cached); err != nil {
+			logger.ErrorF("cached claim for address %s err, %s", address, err)
+		}
+
+		return cached
+	})
+
+	if flight == nil {
+		server.scheduleSync(address)
+		return nil
+	}
+
+	select {
+	case <-flight.done:
+		return flight.cached
+	case <-time.After(server.claimDeadline):
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package insight
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/stretchr/testify/require"
+)
+
+func newClaimStatusServer() *Server {
+	OpenLogger()
+
+	return &Server{
+		network:      claim.MainNet,
+		sysfee:       &sysFeeIndex{height: 100},
+		syncStore:    newMemorySyncStore(),
+		claimCache:   newLRUClaimCache(10, time.Minute),
+		claimFlights: newClaimFlights(2),
+	}
+}
+
+func TestClaimResult(t *testing.T) {
+	server := newClaimStatusServer()
+
+	result := server.claimResult("a")
+	require.Equal(t, ClaimPending, result.Status)
+	require.Equal(t, int64(-1), result.Height)
+
+	pending, err := server.syncStore.pending("a")
+	require.NoError(t, err)
+	require.True(t, pending)
+
+	// a pending claim has no gas, not a zero one
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Claims": null, "height": -1, "status": "pending"}`, string(data))
+
+	jobs, err := server.syncStore.lease(time.Now(), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	cached := testCachedClaim(100)
+	cached.Computed = time.Now()
+
+	require.NoError(t, server.claimCache.Set("a", cached))
+
+	// cached but the refresh is still syncing
+	result = server.claimResult("a")
+	require.Equal(t, ClaimStale, result.Status)
+
+	_, err = server.syncStore.complete(jobs[0], 0, time.Now())
+	require.NoError(t, err)
+
+	result = server.claimResult("a")
+	require.Equal(t, ClaimFresh, result.Status)
+	require.Equal(t, int64(100), result.Height)
+	require.Equal(t, "1.5", result.Available)
+	require.Equal(t, "0.25", result.Unavailable)
+	require.True(t, cached.Computed.Equal(*result.Computed))
+
+	// a block touched a
+	server.scheduleSync("a")
+
+	result = server.claimResult("a")
+	require.Equal(t, ClaimStale, result.Status)
+	require.Equal(t, "1.5", result.Available)
+}
+
+func TestClaimFallback(t *testing.T) {
+	server := newClaimStatusServer()
+
+	compute := func(address string) (*CachedClaim, error) {
+		return testCachedClaim(100), nil
+	}
+
+	// no deadline, the sync workers compute it
+	require.Nil(t, server.claimFallback("a", compute))
+
+	pending, err := server.syncStore.pending("a")
+	require.NoError(t, err)
+	require.True(t, pending)
+
+	server.claimDeadline = time.Second
+
+	cached := server.claimFallback("b", compute)
+	require.Equal(t, testCachedClaim(100), cached)
+
+	cached, err = server.claimCache.Get("b")
+	require.NoError(t, err)
+	require.Equal(t, testCachedClaim(100), cached)
+
+	pending, err = server.syncStore.pending("b")
+	require.NoError(t, err)
+	require.False(t, pending)
+
+	require.Nil(t, server.claimFallback("c", func(address string) (*CachedClaim, error) {
+		return nil, errors.New("datastore")
+	}))
+
+	pending, err = server.syncStore.pending("c")
+	require.NoError(t, err)
+	require.True(t, pending)
+
+	// the deadline passes, requests for d share the computation in flight and schedule no refresh
+	server.claimDeadline = 10 * time.Millisecond
+
+	release := make(chan struct{})
+	computed := int32(0)
+
+	slow := func(address string) (*CachedClaim, error) {
+		atomic.AddInt32(&computed, 1)
+		<-release
+		return testCachedClaim(100), nil
+	}
+
+	shared := claimFallbackShared.Value()
+
+	require.Nil(t, server.claimFallback("d", slow))
+	require.Nil(t, server.claimFallback("d", slow))
+	require.Equal(t, shared+1, claimFallbackShared.Value())
+
+	pending, err = server.syncStore.pending("d")
+	require.NoError(t, err)
+	require.False(t, pending)
+
+	// both slots are taken, the sync workers compute f
+	saturated := claimFallbackSaturated.Value()
+
+	require.Nil(t, server.claimFallback("e", slow))
+	require.Nil(t, server.claimFallback("f", slow))
+	require.Equal(t, saturated+1, claimFallbackSaturated.Value())
+
+	pending, err = server.syncStore.pending("f")
+	require.NoError(t, err)
+	require.True(t, pending)
+
+	close(release)
+
+	// the claims computed after the deadline are still cached
+	require.Eventually(t, func() bool {
+		d, _ := server.claimCache.Get("d")
+		e, _ := server.claimCache.Get("e")
+		return d != nil && e != nil
+	}, time.Second, 5*time.Millisecond)
+
+	require.Equal(t, int32(2), atomic.LoadInt32(&computed))
+}
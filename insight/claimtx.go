@@ -0,0 +1,201 @@
+package insight
+
+import (
+	"encoding/hex"
+
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+)
+
+type buildClaimTxParams struct {
+	Address string `json:"address" desc:"neo address or script hash"`
+	To      string `json:"to" rpc:"optional" desc:"address receiving the claimed gas, default is address"`
+}
+
+// ClaimTransaction unsigned claim transaction, the client signs hash (the sha256 of signData), attaches
+// the witness and broadcasts it
+type ClaimTransaction struct {
+	Address  string      `json:"address"`
+	To       string      `json:"to"`
+	Height   int64       `json:"height" desc:"index tip the claim is computed at"`
+	Amount   string      `json:"amount"`
+	Claims   []*rpc.UTXO `json:"claims"`
+	TxID     string      `json:"txid"`
+	Raw      string      `json:"raw" desc:"unsigned serialized transaction in hex"`
+	SignData string      `json:"signData" desc:"sign data in hex"`
+	Hash     string      `json:"hash" desc:"sha256 of sign data in hex"`
+}
+
+func (server *Server) buildClaimTx(params interface{}) (interface{}, *JSONRPCError) {
+	buildParams := params.(*buildClaimTxParams)
+
+	address, rpcerr := normalizeAddress(buildParams.Address)
+
+	if rpcerr != nil {
+		return nil, rpcerr
+	}
+
+	to := address
+
+	if buildParams.To != "" {
+		if to, rpcerr = normalizeAddress(buildParams.To); rpcerr != nil {
+			return nil, rpcerr
+		}
+	}
+
+	// computed fresh, the cached claim may miss utxos spent after it was cached
+	tip, details, err := server.claimDetails(address)
+
+	if err != nil {
+		return nil, asJSONRPCError(err)
+	}
+
+	claims := make([]*rpc.UTXO, 0)
+
+	for _, detail := range details {
+		if detail.Available {
+			claims = append(claims, detail.UTXO)
+		}
+	}
+
+	_, available := claim.SumDetails(details)
+
+	if len(claims) == 0 || available <= 0 {
+		return nil, appErrorf(
+			JSONRPCNothingToClaim, map[string]interface{}{"address": address, "height": tip},
+			"address %s has no available gas to claim", address,
+		)
+	}
+
+	claimTx, err := server.network.BuildClaimTx(claims, available, to)
+
+	if err != nil {
+		return nil, asJSONRPCError(err)
+	}
+
+	return newClaimTransaction(address, to, tip, claimTx), nil
+}
+
+func newClaimTransaction(address, to string, tip int64, claimTx *claim.ClaimTx) *ClaimTransaction {
+	return &ClaimTransaction{
+		Address:  address,
+		To:       to,
+		Height:   tip,
+		Amount:   claim.FormatFixed8(claimTx.Amount),
+		Claims:   claimTx.Claims,
+		TxID:     claimTx.TxID,
+		Raw:      hex.EncodeToString(claimTx.Raw),
+		SignData: hex.EncodeToString(claimTx.SignData),
+		Hash:     hex.EncodeToString(claimTx.Hash),
+	}
+}
+
+type planClaimTxsParams struct {
+	Address   string `json:"address" desc:"neo address or script hash"`
+	To        string `json:"to" rpc:"optional" desc:"address receiving the claimed gas, default is address"`
+	Serialize bool   `json:"serialize" rpc:"optional" desc:"include the unsigned transactions"`
+}
+
+// ClaimTxPlans ordered claim transactions claiming all available gas of address within the
+// transaction count and size limits
+type ClaimTxPlans struct {
+	Address string         `json:"address"`
+	To      string         `json:"to"`
+	Height  int64          `json:"height" desc:"index tip the claim is computed at"`
+	Amount  string         `json:"amount" desc:"sum of the plans' amount"`
+	Plans   []*ClaimTxPlan `json:"plans"`
+}
+
+// ClaimTxPlan one claim transaction of the plans, the transaction fields are set when serialize is requested
+type ClaimTxPlan struct {
+	Index    int         `json:"index"`
+	Amount   string      `json:"amount"`
+	Claims   []*rpc.UTXO `json:"claims"`
+	Size     int         `json:"size" desc:"serialized size with a single signature witness"`
+	TxID     string      `json:"txid,omitempty"`
+	Raw      string      `json:"raw,omitempty" desc:"unsigned serialized transaction in hex"`
+	SignData string      `json:"signData,omitempty" desc:"sign data in hex"`
+	Hash     string      `json:"hash,omitempty" desc:"sha256 of sign data in hex"`
+}
+
+// claimTxLimits claim transaction limits, the default byte limit is the node's max transaction size
+func (server *Server) claimTxLimits() *claim.PlanLimits {
+	return &claim.PlanLimits{
+		MaxClaims:   int(server.cnf.GetInt64("insight.claim_tx.max_claims", 500)),
+		MaxBytes:    int(server.cnf.GetInt64("insight.claim_tx.max_bytes", 102400)),
+		WitnessSize: int(server.cnf.GetInt64("insight.claim_tx.witness_size", claim.SingleSigWitnessSize)),
+	}
+}
+
+func (server *Server) planClaimTxs(params interface{}) (interface{}, *JSONRPCError) {
+	planParams := params.(*planClaimTxsParams)
+
+	address, rpcerr := normalizeAddress(planParams.Address)
+
+	if rpcerr != nil {
+		return nil, rpcerr
+	}
+
+	to := address
+
+	if planParams.To != "" {
+		if to, rpcerr = normalizeAddress(planParams.To); rpcerr != nil {
+			return nil, rpcerr
+		}
+	}
+
+	tip, details, err := server.claimDetails(address)
+
+	if err != nil {
+		return nil, asJSONRPCError(err)
+	}
+
+	limits := server.claimTxLimits()
+
+	claimTxs, err := server.network.PlanClaimTxs(details, to, limits)
+
+	if err != nil {
+		return nil, asJSONRPCError(err)
+	}
+
+	if len(claimTxs) == 0 {
+		return nil, appErrorf(
+			JSONRPCNothingToClaim, map[string]interface{}{"address": address, "height": tip},
+			"address %s has no available gas to claim", address,
+		)
+	}
+
+	result := &ClaimTxPlans{
+		Address: address,
+		To:      to,
+		Height:  tip,
+		Plans:   make([]*ClaimTxPlan, 0, len(claimTxs)),
+	}
+
+	amount := tx.Fixed8(0)
+
+	for i, claimTx := range claimTxs {
+		plan := &ClaimTxPlan{
+			Index:  i,
+			Amount: claim.FormatFixed8(claimTx.Amount),
+			Claims: claimTx.Claims,
+			Size:   len(claimTx.Raw) + limits.WitnessSize,
+		}
+
+		if planParams.Serialize {
+			plan.TxID = claimTx.TxID
+			plan.Raw = hex.EncodeToString(claimTx.Raw)
+			plan.SignData = hex.EncodeToString(claimTx.SignData)
+			plan.Hash = hex.EncodeToString(claimTx.Hash)
+		}
+
+		amount += claimTx.Amount
+
+		result.Plans = append(result.Plans, plan)
+	}
+
+	result.Amount = claim.FormatFixed8(amount)
+
+	return result, nil
+}
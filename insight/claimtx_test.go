@@ -0,0 +1,21 @@
+package insight
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildClaimTxInvalidTo(t *testing.T) {
+	server := newTestServer(t)
+
+	response := server.call(&RPCRequest{
+		JSONRPC: "2.0",
+		Method:  "buildClaimTx",
+		Params:  []byte(`{"address": "` + testAddress + `", "to": "AanTXadhgdHzGbmy5ZBPXxR4iPHMivzVPz"}`),
+		ID:      []byte(`1`),
+	})
+
+	require.NotNil(t, response.Error)
+	require.Equal(t, JSONRPCInvalidAddress, response.Error.Code)
+}
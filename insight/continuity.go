@@ -0,0 +1,120 @@
+package insight
+
+import (
+	"strconv"
+
+	"github.com/go-xorm/xorm"
+	"github.com/inwecrypto/neo-insight/claim"
+)
+
+// checkBlocks find missing and duplicate heights of neo_block in [start, end], end -1 means up to
+// the highest indexed block
+func checkBlocks(engine *xorm.Engine, start, end int64) (*claim.Continuity, error) {
+
+	if end == -1 {
+		rows, err := engine.QueryString(`select coalesce(max(block), -1)::text as max from neo_block`)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if end, err = strconv.ParseInt(rows[0]["max"], 10, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	continuity := &claim.Continuity{Start: start, End: end}
+
+	if end < start {
+		return continuity, nil
+	}
+
+	rows, err := engine.QueryString(
+		`select min(block)::text as min, max(block)::text as max from neo_block where block >= ? and block <= ?`,
+		start, end,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 || rows[0]["min"] == "" {
+		continuity.Gaps = append(continuity.Gaps, &claim.Gap{From: start, To: end})
+		return continuity, nil
+	}
+
+	min, err := strconv.ParseInt(rows[0]["min"], 10, 64)
+
+	if err != nil {
+		return nil, err
+	}
+
+	max, err := strconv.ParseInt(rows[0]["max"], 10, 64)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if min > start {
+		continuity.Gaps = append(continuity.Gaps, &claim.Gap{From: start, To: min - 1})
+	}
+
+	rows, err = engine.QueryString(
+		`select block, next from (
+			select block, lead(block) over (order by block) as next
+			from (select distinct block from neo_block where block >= ? and block <= ?) as heights
+		) as t where next > block + 1 order by block`,
+		start, end,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		block, err := strconv.ParseInt(row["block"], 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		next, err := strconv.ParseInt(row["next"], 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		continuity.Gaps = append(continuity.Gaps, &claim.Gap{From: block + 1, To: next - 1})
+	}
+
+	if max < end {
+		continuity.Gaps = append(continuity.Gaps, &claim.Gap{From: max + 1, To: end})
+	}
+
+	rows, err = engine.QueryString(
+		`select block from neo_block where block >= ? and block <= ? group by block having count(*) > 1 order by block`,
+		start, end,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		block, err := strconv.ParseInt(row["block"], 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		continuity.Duplicates = append(continuity.Duplicates, block)
+	}
+
+	return continuity, nil
+}
+
+// CheckBlocks report missing and duplicate heights of neo_block in [start, end], end -1 means up to
+// the highest indexed block
+func (server *Server) CheckBlocks(start, end int64) (*claim.Continuity, error) {
+	return checkBlocks(server.engine, start, end)
+}
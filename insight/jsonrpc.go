@@ -1,6 +1,14 @@
 package insight
 
-import "fmt"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ybbus/jsonrpc"
+)
 
 // Errors .
 const (
@@ -11,10 +19,56 @@ const (
 	JSONRPCInnerError     = -32603
 )
 
+// Application errors, allocated in the jsonrpc 2.0 reserved server error range
+const (
+	JSONRPCInvalidAddress       = -32001
+	JSONRPCUnknownAsset         = -32002
+	JSONRPCIndexerBehind        = -32003
+	JSONRPCCacheCold            = -32004
+	JSONRPCDatastoreUnavailable = -32005
+	JSONRPCMethodDenied         = -32006
+	JSONRPCIncompleteBlocks     = -32007
+	JSONRPCNothingToClaim       = -32008
+)
+
+// Error reasons carried by ErrorData, clients branch on them
+const (
+	ReasonInternal             = "internal"
+	ReasonInvalidAddress       = "invalid_address"
+	ReasonUnknownAsset         = "unknown_asset"
+	ReasonIndexerBehind        = "indexer_behind"
+	ReasonCacheCold            = "cache_cold"
+	ReasonDatastoreUnavailable = "datastore_unavailable"
+	ReasonMethodDenied         = "method_denied"
+	ReasonUpstreamUnavailable  = "upstream_unavailable"
+	ReasonIncompleteBlocks     = "incomplete_blocks"
+	ReasonNothingToClaim       = "nothing_to_claim"
+)
+
+var errorReasons = map[int]string{
+	JSONRPCInnerError:           ReasonInternal,
+	JSONRPCInvalidAddress:       ReasonInvalidAddress,
+	JSONRPCUnknownAsset:         ReasonUnknownAsset,
+	JSONRPCIndexerBehind:        ReasonIndexerBehind,
+	JSONRPCCacheCold:            ReasonCacheCold,
+	JSONRPCDatastoreUnavailable: ReasonDatastoreUnavailable,
+	JSONRPCMethodDenied:         ReasonMethodDenied,
+	JSONRPCIncompleteBlocks:     ReasonIncompleteBlocks,
+	JSONRPCNothingToClaim:       ReasonNothingToClaim,
+}
+
+// ErrorData structured data payload of application and internal errors
+type ErrorData struct {
+	Reason        string                 `json:"reason"`
+	CorrelationID string                 `json:"correlationId,omitempty"`
+	Details       map[string]interface{} `json:"details,omitempty"`
+}
+
 // JSONRPCError .
 type JSONRPCError struct {
 	ID      int
 	Message string
+	Data    interface{}
 }
 
 func errorf(id int, fmtstr string, args ...interface{}) *JSONRPCError {
@@ -23,3 +77,141 @@ func errorf(id int, fmtstr string, args ...interface{}) *JSONRPCError {
 		Message: fmt.Sprintf(fmtstr, args...),
 	}
 }
+
+// appErrorf create application error with structured data payload
+func appErrorf(id int, details map[string]interface{}, fmtstr string, args ...interface{}) *JSONRPCError {
+	return &JSONRPCError{
+		ID:      id,
+		Message: fmt.Sprintf(fmtstr, args...),
+		Data: &ErrorData{
+			Reason:  errorReasons[id],
+			Details: details,
+		},
+	}
+}
+
+// AppError error raised by the service layer, which maps to an application jsonrpc error
+type AppError struct {
+	Code    int
+	Details map[string]interface{}
+	Err     error
+}
+
+func (err *AppError) Error() string {
+	return err.Err.Error()
+}
+
+func newAppError(code int, details map[string]interface{}, fmtstr string, args ...interface{}) *AppError {
+	return &AppError{
+		Code:    code,
+		Details: details,
+		Err:     fmt.Errorf(fmtstr, args...),
+	}
+}
+
+// asJSONRPCError convert service layer error to jsonrpc error, errors other than *AppError
+// are reported as inner error with correlation id, the detail is only logged. Datastore errors
+// wrap driver errors, they are reported with a fixed message and correlation id as well
+func asJSONRPCError(err error) *JSONRPCError {
+	apperr, ok := err.(*AppError)
+
+	if ok && apperr.Code != JSONRPCDatastoreUnavailable {
+		return appErrorf(apperr.Code, apperr.Details, "%s", apperr.Err)
+	}
+
+	correlationID := newCorrelationID()
+
+	if ok {
+		logger.ErrorF("[%s] datastore error: %s", correlationID, apperr.Err)
+
+		return &JSONRPCError{
+			ID:      JSONRPCDatastoreUnavailable,
+			Message: "datastore unavailable",
+			Data: &ErrorData{
+				Reason:        ReasonDatastoreUnavailable,
+				CorrelationID: correlationID,
+				Details:       apperr.Details,
+			},
+		}
+	}
+
+	logger.ErrorF("[%s] inner error: %s", correlationID, err)
+
+	return &JSONRPCError{
+		ID:      JSONRPCInnerError,
+		Message: "internal error",
+		Data: &ErrorData{
+			Reason:        ReasonInternal,
+			CorrelationID: correlationID,
+		},
+	}
+}
+
+// RPCRequest jsonrpc 2.0 request object, the ID is nil for notification
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// IsNotification check if the request is a notification (without id member)
+func (request *RPCRequest) IsNotification() bool {
+	return request.ID == nil
+}
+
+// RPCResponse jsonrpc 2.0 response object, the ID echo the request id as is
+type RPCResponse struct {
+	JSONRPC string            `json:"jsonrpc"`
+	Result  interface{}       `json:"result,omitempty"`
+	Error   *jsonrpc.RPCError `json:"error,omitempty"`
+	ID      json.RawMessage   `json:"id"`
+}
+
+// MarshalJSON a response has exactly one of result and error, a nil result is null
+func (response RPCResponse) MarshalJSON() ([]byte, error) {
+	if response.Error != nil {
+		return json.Marshal(&struct {
+			JSONRPC string            `json:"jsonrpc"`
+			Error   *jsonrpc.RPCError `json:"error"`
+			ID      json.RawMessage   `json:"id"`
+		}{response.JSONRPC, response.Error, response.ID})
+	}
+
+	return json.Marshal(&struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  interface{}     `json:"result"`
+		ID      json.RawMessage `json:"id"`
+	}{response.JSONRPC, response.Result, response.ID})
+}
+
+func newRPCResult(id json.RawMessage, result interface{}) *RPCResponse {
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result:  result,
+	}
+}
+
+func newRPCError(id json.RawMessage, code int, message string, data interface{}) *RPCResponse {
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &jsonrpc.RPCError{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+	}
+}
+
+// newCorrelationID create random id to correlate error response with server log
+func newCorrelationID() string {
+	data := make([]byte, 8)
+
+	if _, err := rand.Read(data); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(data)
+}
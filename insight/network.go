@@ -0,0 +1,48 @@
+package insight
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dynamicgo/config"
+	"github.com/inwecrypto/neo-insight/claim"
+)
+
+// loadNetwork select the claim network profile by insight.network, custom profiles are defined in
+// insight.networks by name and take precedence over the built-in mainnet/testnet profiles
+func loadNetwork(cnf *config.Config) (*claim.Params, error) {
+
+	name := cnf.GetString("insight.network", claim.MainNet.Name)
+
+	if cnf.Has("insight.networks") {
+		var networks map[string]json.RawMessage
+
+		if err := cnf.GetObject("insight.networks", &networks); err != nil {
+			return nil, fmt.Errorf("load insight.networks err, %s", err)
+		}
+
+		if data, ok := networks[name]; ok {
+			network, err := claim.ParseParams(data)
+
+			if err != nil {
+				return nil, fmt.Errorf("load insight.networks.%s err, %s", name, err)
+			}
+
+			if network.Name == "" {
+				network.Name = name
+			}
+
+			logger.InfoF("use custom network %s", name)
+
+			return network, nil
+		}
+	}
+
+	network, ok := claim.GetParams(name)
+
+	if !ok {
+		return nil, fmt.Errorf("unknown network %s", name)
+	}
+
+	return network, nil
+}
@@ -0,0 +1,56 @@
+package insight
+
+import (
+	"testing"
+
+	"github.com/dynamicgo/config"
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadNetwork(t *testing.T) {
+	OpenLogger()
+
+	cnf, err := config.New([]byte(`{}`))
+
+	require.NoError(t, err)
+
+	network, err := loadNetwork(cnf)
+
+	require.NoError(t, err)
+	require.Equal(t, claim.MainNet, network)
+
+	cnf, err = config.New([]byte(`{"insight": {
+		"network": "privnet",
+		"networks": {
+			"privnet": {
+				"generation": [8, 4],
+				"decrementInterval": 1000,
+				"totalNEO": 100000000,
+				"neoAsset": "0x0000000000000000000000000000000000000000000000000000000000000001",
+				"gasAsset": "0x0000000000000000000000000000000000000000000000000000000000000002"
+			}
+		}
+	}}`))
+
+	require.NoError(t, err)
+
+	network, err = loadNetwork(cnf)
+
+	require.NoError(t, err)
+	require.Equal(t, "privnet", network.Name)
+	require.Equal(t, int64(1000), network.DecrementInterval)
+
+	asset, rpcerr := normalizeAsset("gas", network)
+
+	require.Nil(t, rpcerr)
+	require.Equal(t, network.GasAsset, asset)
+
+	cnf, err = config.New([]byte(`{"insight": {"network": "unknown"}}`))
+
+	require.NoError(t, err)
+
+	_, err = loadNetwork(cnf)
+
+	require.Error(t, err)
+}
@@ -0,0 +1,262 @@
+package insight
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// OpenRPCVersion the OpenRPC specification version the generated document follows
+const OpenRPCVersion = "1.2.6"
+
+// OpenRPCDocument OpenRPC service description document
+type OpenRPCDocument struct {
+	OpenRPC string           `json:"openrpc"`
+	Info    OpenRPCInfo      `json:"info"`
+	Methods []*OpenRPCMethod `json:"methods"`
+}
+
+// OpenRPCInfo .
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCMethod .
+type OpenRPCMethod struct {
+	Name           string                `json:"name"`
+	Summary        string                `json:"summary,omitempty"`
+	ParamStructure string                `json:"paramStructure"`
+	Params         []*OpenRPCDescriptor  `json:"params"`
+	Result         *OpenRPCDescriptor    `json:"result"`
+	Errors         []*OpenRPCErrorObject `json:"errors,omitempty"`
+}
+
+// OpenRPCDescriptor content descriptor of param or result
+type OpenRPCDescriptor struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Required    bool                   `json:"required,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+// OpenRPCErrorObject .
+type OpenRPCErrorObject struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorMessages default message of error codes listed in OpenRPC document
+var errorMessages = map[int]string{
+	JSONRPCParserError:    "parse error",
+	JSONRPCInvalidRequest: "invalid request",
+	JSONRPCMethodNotFound: "method not found",
+	JSONRPCInvalidParams:  "invalid params",
+	JSONRPCInnerError:     "internal error",
+
+	JSONRPCInvalidAddress:       "invalid address",
+	JSONRPCUnknownAsset:         "unknown asset",
+	JSONRPCIndexerBehind:        "indexer behind",
+	JSONRPCCacheCold:            "cache cold",
+	JSONRPCDatastoreUnavailable: "datastore unavailable",
+	JSONRPCMethodDenied:         "method denied",
+	JSONRPCIncompleteBlocks:     "incomplete blocks",
+	JSONRPCNothingToClaim:       "nothing to claim",
+}
+
+// methodErrors error codes every extend method can return: params binding, recovered panics and the
+// proxy deny policy
+var methodErrors = []int{JSONRPCInvalidParams, JSONRPCInnerError, JSONRPCMethodDenied}
+
+// describe attach OpenRPC metadata to method, result is the prototype value of method result, errors
+// the codes of method besides methodErrors
+func (m *method) describe(summary string, result interface{}, errors ...int) *method {
+	m.summary = summary
+	m.result = reflect.TypeOf(result)
+	m.errors = errors
+
+	return m
+}
+
+func (m *method) openRPC(name string) *OpenRPCMethod {
+	spec := &OpenRPCMethod{
+		Name:           name,
+		Summary:        m.summary,
+		ParamStructure: "either",
+		Params:         make([]*OpenRPCDescriptor, 0),
+		Result: &OpenRPCDescriptor{
+			Name:   "result",
+			Schema: map[string]interface{}{},
+		},
+	}
+
+	for _, field := range m.fields() {
+		structField := m.params.Field(field.index)
+
+		spec.Params = append(spec.Params, &OpenRPCDescriptor{
+			Name:        field.name,
+			Description: structField.Tag.Get("desc"),
+			Required:    !field.optional,
+			Schema:      jsonSchema(structField.Type, nil),
+		})
+	}
+
+	if m.result != nil {
+		spec.Result.Schema = jsonSchema(m.result, nil)
+	}
+
+	listed := make(map[int]bool)
+
+	for _, code := range append(append([]int{}, methodErrors...), m.errors...) {
+		if listed[code] {
+			continue
+		}
+
+		listed[code] = true
+
+		spec.Errors = append(spec.Errors, &OpenRPCErrorObject{
+			Code:    code,
+			Message: errorMessages[code],
+		})
+	}
+
+	return spec
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// jsonSchema generate json schema from go type follow encoding/json marshal rules
+func jsonSchema(typ reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchema(typ.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(typ.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting == nil {
+			visiting = make(map[reflect.Type]bool)
+		}
+
+		if visiting[typ] {
+			return map[string]interface{}{"type": "object"}
+		}
+
+		visiting[typ] = true
+		defer delete(visiting, typ)
+
+		properties := make(map[string]interface{})
+		required := make([]string, 0, typ.NumField())
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+
+			if field.PkgPath != "" {
+				continue
+			}
+
+			tag := strings.Split(field.Tag.Get("json"), ",")
+			name := tag[0]
+
+			if name == "-" {
+				continue
+			}
+
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = jsonSchema(field.Type, visiting)
+
+			// omitempty fields are left out of the encoded object when empty
+			if !hasOption(tag[1:], "omitempty") {
+				required = append(required, name)
+			}
+		}
+
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+
+		return schema
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OpenRPC generate OpenRPC document of registered extend methods
+func (server *Server) OpenRPC() *OpenRPCDocument {
+	names := make([]string, 0, len(server.dispatch))
+
+	for name := range server.dispatch {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	doc := &OpenRPCDocument{
+		OpenRPC: OpenRPCVersion,
+		Info: OpenRPCInfo{
+			Title:   "neo-insight extend api",
+			Version: server.cnf.GetString("insight.version", "1.0.0"),
+		},
+		Methods: make([]*OpenRPCMethod, 0, len(names)),
+	}
+
+	for _, name := range names {
+		doc.Methods = append(doc.Methods, server.dispatch[name].openRPC(name))
+	}
+
+	return doc
+}
+
+type discoverParams struct{}
+
+func (server *Server) discover(params interface{}) (interface{}, *JSONRPCError) {
+	return server.OpenRPC(), nil
+}
+
+// ServeOpenRPC serve the OpenRPC document over http GET
+func (server *Server) ServeOpenRPC(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeJSON(w, server.OpenRPC())
+}
@@ -0,0 +1,107 @@
+package insight
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/dynamicgo/config"
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) *Server {
+	OpenLogger()
+
+	cnf, err := config.New([]byte(`{}`))
+
+	require.NoError(t, err)
+
+	server := &Server{
+		cnf:          cnf,
+		network:      claim.MainNet,
+		dispatch:     make(map[string]*method),
+		batchLimit:   100,
+		batchWorkers: 4,
+	}
+
+	server.registerMethods()
+
+	return server
+}
+
+func TestOpenRPCDescribeAllMethods(t *testing.T) {
+	server := newTestServer(t)
+
+	doc := server.OpenRPC()
+
+	require.Equal(t, len(server.dispatch), len(doc.Methods))
+
+	for _, m := range doc.Methods {
+		require.NotEmpty(t, m.Summary, "method %s summary", m.Name)
+		require.NotEmpty(t, m.Result.Schema, "method %s result schema", m.Name)
+	}
+
+	_, err := json.Marshal(doc)
+
+	require.NoError(t, err)
+}
+
+func TestOpenRPCParams(t *testing.T) {
+	server := newTestServer(t)
+
+	var balance *OpenRPCMethod
+
+	for _, m := range server.OpenRPC().Methods {
+		if m.Name == "balance" {
+			balance = m
+		}
+	}
+
+	require.NotNil(t, balance)
+	require.Len(t, balance.Params, 2)
+	require.Equal(t, "address", balance.Params[0].Name)
+	require.Equal(t, "asset", balance.Params[1].Name)
+	require.True(t, balance.Params[0].Required)
+	require.Equal(t, "string", balance.Params[1].Schema["type"])
+}
+
+func TestOpenRPCErrors(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, m := range server.OpenRPC().Methods {
+		codes := make(map[int]bool)
+
+		for _, e := range m.Errors {
+			require.False(t, codes[e.Code], "method %s error %d listed twice", m.Name, e.Code)
+			require.NotEmpty(t, e.Message, "method %s error %d message", m.Name, e.Code)
+
+			codes[e.Code] = true
+		}
+
+		for _, code := range []int{JSONRPCInvalidParams, JSONRPCInnerError, JSONRPCMethodDenied} {
+			require.True(t, codes[code], "method %s error %d", m.Name, code)
+		}
+	}
+}
+
+func TestJSONSchemaRequired(t *testing.T) {
+	type result struct {
+		Address string `json:"address"`
+		Height  int64
+		Error   string `json:"error,omitempty"`
+		Skipped string `json:"-"`
+	}
+
+	schema := jsonSchema(reflect.TypeOf(&result{}), nil)
+
+	require.Equal(t, []string{"address", "Height"}, schema["required"])
+	require.Len(t, schema["properties"], 3)
+
+	type empty struct {
+		Error string `json:"error,omitempty"`
+	}
+
+	_, ok := jsonSchema(reflect.TypeOf(empty{}), nil)["required"]
+	require.False(t, ok)
+}
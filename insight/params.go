@@ -0,0 +1,176 @@
+package insight
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// method registered extend method, params is the struct type the request params bind to
+type method struct {
+	params  reflect.Type
+	handler handler
+	summary string
+	result  reflect.Type
+	errors  []int
+}
+
+// paramField one bindable field of method params struct, fields are positional in declare order
+type paramField struct {
+	index    int
+	name     string
+	optional bool
+}
+
+func (m *method) fields() []*paramField {
+	return paramFields(m.params)
+}
+
+func paramFields(typ reflect.Type) []*paramField {
+	fields := make([]*paramField, 0, typ.NumField())
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields = append(fields, &paramField{
+			index:    i,
+			name:     name,
+			optional: field.Tag.Get("rpc") == "optional",
+		})
+	}
+
+	return fields
+}
+
+// bind decode positional (array) or named (object) params into new method params struct
+func (m *method) bind(raw json.RawMessage) (interface{}, *JSONRPCError) {
+
+	value := reflect.New(m.params)
+	fields := m.fields()
+	bound := make(map[string]bool)
+
+	raw = bytes.TrimSpace(raw)
+
+	switch {
+	case isNull(raw):
+
+	case raw[0] == '[':
+		var params []json.RawMessage
+
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, errorf(JSONRPCInvalidParams, "params must be array or object")
+		}
+
+		// extra positional params are ignored as the original handlers did, clients send claim
+		// the address with the asset
+		if len(params) > len(fields) {
+			params = params[:len(fields)]
+		}
+
+		for i, param := range params {
+			if isNull(param) {
+				continue
+			}
+
+			if err := bindField(value.Elem(), fields[i], param); err != nil {
+				return nil, err
+			}
+
+			bound[fields[i].name] = true
+		}
+
+	case raw[0] == '{':
+		var params map[string]json.RawMessage
+
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, errorf(JSONRPCInvalidParams, "params must be array or object")
+		}
+
+		for _, field := range fields {
+			param, ok := params[field.name]
+
+			if !ok {
+				continue
+			}
+
+			delete(params, field.name)
+
+			if isNull(param) {
+				continue
+			}
+
+			if err := bindField(value.Elem(), field, param); err != nil {
+				return nil, err
+			}
+
+			bound[field.name] = true
+		}
+
+		for name := range params {
+			return nil, errorf(JSONRPCInvalidParams, "unknown parameter %s", name)
+		}
+
+	default:
+		return nil, errorf(JSONRPCInvalidParams, "params must be array or object")
+	}
+
+	for _, field := range fields {
+		if !field.optional && !bound[field.name] {
+			return nil, errorf(JSONRPCInvalidParams, "expect %s parameter", field.name)
+		}
+	}
+
+	return value.Interface(), nil
+}
+
+// isNull absent or json null param, a null param is missing
+func isNull(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+
+	return len(raw) == 0 || bytes.Equal(raw, []byte("null"))
+}
+
+func bindField(value reflect.Value, field *paramField, param json.RawMessage) *JSONRPCError {
+	target := value.Field(field.index)
+
+	if err := json.Unmarshal(param, target.Addr().Interface()); err != nil {
+		return errorf(JSONRPCInvalidParams, "%s parameter must be %s", field.name, typeName(target.Type()))
+	}
+
+	return nil
+}
+
+func typeName(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Ptr:
+		return typeName(typ.Elem())
+	default:
+		return "object"
+	}
+}
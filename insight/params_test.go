@@ -0,0 +1,59 @@
+package insight
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type bindParams struct {
+	Address string `json:"address"`
+	Limit   int    `json:"limit" rpc:"optional"`
+}
+
+func TestBindParams(t *testing.T) {
+	m := &method{params: reflect.TypeOf(bindParams{})}
+
+	bind := func(raw string) (*bindParams, *JSONRPCError) {
+		params, err := m.bind(json.RawMessage(raw))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return params.(*bindParams), nil
+	}
+
+	params, err := bind(`["a", 2]`)
+	require.Nil(t, err)
+	require.Equal(t, &bindParams{Address: "a", Limit: 2}, params)
+
+	params, err = bind(`{"address": "a"}`)
+	require.Nil(t, err)
+	require.Equal(t, &bindParams{Address: "a"}, params)
+
+	// extra positional params are ignored, claim clients send the asset
+	params, err = bind(`["a", 2, "0xc56f33fc6ecfcd0c225c4ab356fee59390af8560be0e930faebe74a6daff7c9b"]`)
+	require.Nil(t, err)
+	require.Equal(t, &bindParams{Address: "a", Limit: 2}, params)
+
+	_, err = bind(`{"address": "a", "asset": "neo"}`)
+	require.NotNil(t, err)
+	require.Equal(t, JSONRPCInvalidParams, err.ID)
+
+	// null is missing
+	for _, raw := range []string{`[null]`, `{"address": null}`, `null`, `[]`} {
+		_, err = bind(raw)
+		require.NotNil(t, err, raw)
+		require.Equal(t, JSONRPCInvalidParams, err.ID, raw)
+	}
+
+	params, err = bind(`["a", null]`)
+	require.Nil(t, err)
+	require.Equal(t, &bindParams{Address: "a"}, params)
+
+	_, err = bind(`["a", "two"]`)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,210 @@
+package insight
+
+import (
+	"strconv"
+
+	"github.com/inwecrypto/neo-insight/claim"
+)
+
+type claimProjectionParams struct {
+	Address string `json:"address" desc:"neo address or script hash"`
+	Height  *int64 `json:"height" rpc:"optional" desc:"target block height"`
+	Time    *int64 `json:"time" rpc:"optional" desc:"target unix timestamp in seconds, used when height is absent"`
+}
+
+// ClaimProjection unavailable gas of address projected to a target height, assuming the unspent
+// utxos stay unspent until then
+type ClaimProjection struct {
+	Address              string                 `json:"address"`
+	Height               int64                  `json:"height" desc:"index tip the projection starts from"`
+	TargetHeight         int64                  `json:"targetHeight"`
+	TargetTime           int64                  `json:"targetTime" desc:"estimated unix timestamp of target height"`
+	Available            string                 `json:"available"`
+	Unavailable          string                 `json:"unavailable"`
+	Generated            string                 `json:"generated" desc:"generation gas of (height, targetHeight]"`
+	SysFee               string                 `json:"sysfee" desc:"estimated sys_fee gas of (height, targetHeight]"`
+	ProjectedUnavailable string                 `json:"projectedUnavailable"`
+	Assumptions          *ProjectionAssumptions `json:"assumptions"`
+}
+
+// ProjectionAssumptions block time and sys_fee statistics of the recent blocks the projection uses
+type ProjectionAssumptions struct {
+	Window          int64   `json:"window" desc:"number of recent blocks sampled"`
+	TipTime         int64   `json:"tipTime" desc:"unix timestamp of the index tip block"`
+	SecondsPerBlock float64 `json:"secondsPerBlock"`
+	SysFeePerBlock  float64 `json:"sysfeePerBlock" desc:"whole GAS sys_fee of all NEO per block"`
+	Unspent         bool    `json:"unspent" desc:"utxos are assumed to stay unspent"`
+}
+
+func (server *Server) getClaimProjection(params interface{}) (interface{}, *JSONRPCError) {
+	projectionParams := params.(*claimProjectionParams)
+
+	address, rpcerr := normalizeAddress(projectionParams.Address)
+
+	if rpcerr != nil {
+		return nil, rpcerr
+	}
+
+	if projectionParams.Height == nil && projectionParams.Time == nil {
+		return nil, errorf(JSONRPCInvalidParams, "expect height or time parameter")
+	}
+
+	tip, details, err := server.claimDetails(address)
+
+	if err != nil {
+		return nil, asJSONRPCError(err)
+	}
+
+	if tip == -1 {
+		return nil, appErrorf(JSONRPCIndexerBehind, nil, "sys_fee index is empty")
+	}
+
+	assumptions, err := server.projectionAssumptions(tip)
+
+	if err != nil {
+		return nil, asJSONRPCError(err)
+	}
+
+	maxBlocks := server.cnf.GetInt64("insight.projection.max_blocks", 50000000)
+
+	target, targetTime, rpcerr := assumptions.target(projectionParams, tip, maxBlocks)
+
+	if rpcerr != nil {
+		return nil, rpcerr
+	}
+
+	// the details accrue through tip, the projection through target
+	projection := server.network.Project(details, tip+1, target+1, assumptions.sysFee(tip, target))
+
+	unavailable, available := claim.SumDetails(details)
+
+	return &ClaimProjection{
+		Address:              address,
+		Height:               tip,
+		TargetHeight:         target,
+		TargetTime:           targetTime,
+		Available:            claim.FormatFixed8(available),
+		Unavailable:          claim.FormatFixed8(unavailable),
+		Generated:            claim.FormatFixed8(projection.Generated),
+		SysFee:               claim.FormatFixed8(projection.SysFee),
+		ProjectedUnavailable: claim.FormatFixed8(projection.Unavailable()),
+		Assumptions:          assumptions,
+	}, nil
+}
+
+// projectionAssumptions sample block time from neo_block.create_time and sys_fee from the index over
+// the recent insight.projection.window blocks up to tip
+func (server *Server) projectionAssumptions(tip int64) (*ProjectionAssumptions, error) {
+
+	window := server.cnf.GetInt64("insight.projection.window", 10000)
+
+	if window > tip {
+		window = tip
+	}
+
+	if window <= 0 {
+		return nil, newAppError(JSONRPCIndexerBehind, map[string]interface{}{"indexed": tip}, "not enough blocks to project from")
+	}
+
+	start := tip - window
+
+	rows, err := server.engine.QueryString(
+		`select block, extract(epoch from min(create_time))::bigint as time from neo_block where block = ? or block = ? group by block`,
+		start, tip,
+	)
+
+	if err != nil {
+		return nil, newAppError(JSONRPCDatastoreUnavailable, nil, "get block time of %d, %d err, %s", start, tip, err)
+	}
+
+	times := make(map[int64]int64)
+
+	for _, row := range rows {
+		block, err := strconv.ParseInt(row["block"], 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		times[block], err = strconv.ParseInt(row["time"], 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	startTime, ok := times[start]
+	tipTime, ok2 := times[tip]
+
+	if !ok || !ok2 || tipTime <= startTime {
+		return nil, newAppError(
+			JSONRPCIncompleteBlocks, map[string]interface{}{"blocks": []int64{start, tip}},
+			"can not get block time statistics of [%d, %d]", start, tip,
+		)
+	}
+
+	startAmount, err := server.sysfee.SysFeeAmount(start)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tipAmount, err := server.sysfee.SysFeeAmount(tip)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProjectionAssumptions{
+		Window:          window,
+		TipTime:         tipTime,
+		SecondsPerBlock: float64(tipTime-startTime) / float64(window),
+		SysFeePerBlock:  float64(tipAmount-startAmount) / float64(window),
+		Unspent:         true,
+	}, nil
+}
+
+// target target height and its estimated time of the height or time parameter, at most maxBlocks
+// above tip
+func (assumptions *ProjectionAssumptions) target(params *claimProjectionParams, tip, maxBlocks int64) (int64, int64, *JSONRPCError) {
+	if params.Height != nil {
+		target := *params.Height
+
+		if target <= tip {
+			return 0, 0, errorf(JSONRPCInvalidParams, "height parameter must be above the index tip %d", tip)
+		}
+
+		if target-tip > maxBlocks {
+			return 0, 0, errorf(JSONRPCInvalidParams, "height parameter must be within %d blocks of the index tip %d", maxBlocks, tip)
+		}
+
+		return target, assumptions.timeAt(tip, target), nil
+	}
+
+	targetTime := *params.Time
+
+	if targetTime <= assumptions.TipTime {
+		return 0, 0, errorf(JSONRPCInvalidParams, "time parameter must be after the index tip time %d", assumptions.TipTime)
+	}
+
+	if float64(targetTime-assumptions.TipTime)/assumptions.SecondsPerBlock > float64(maxBlocks) {
+		return 0, 0, errorf(JSONRPCInvalidParams, "time parameter must be within %d blocks of the index tip time %d", maxBlocks, assumptions.TipTime)
+	}
+
+	return assumptions.heightAt(tip, targetTime), targetTime, nil
+}
+
+// timeAt estimated unix timestamp of block height
+func (assumptions *ProjectionAssumptions) timeAt(tip, height int64) int64 {
+	return assumptions.TipTime + int64(float64(height-tip)*assumptions.SecondsPerBlock)
+}
+
+// heightAt estimated block height at unix timestamp, the last block produced by then
+func (assumptions *ProjectionAssumptions) heightAt(tip, timestamp int64) int64 {
+	return tip + int64(float64(timestamp-assumptions.TipTime)/assumptions.SecondsPerBlock)
+}
+
+// sysFee estimated sys_fee of blocks (tip, height] in whole GAS
+func (assumptions *ProjectionAssumptions) sysFee(tip, height int64) int64 {
+	return int64(assumptions.SysFeePerBlock * float64(height-tip))
+}
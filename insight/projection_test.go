@@ -0,0 +1,79 @@
+package insight
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectionAssumptions(t *testing.T) {
+	assumptions := &ProjectionAssumptions{
+		Window:          1000,
+		TipTime:         1500000000,
+		SecondsPerBlock: 20,
+		SysFeePerBlock:  0.5,
+	}
+
+	require.Equal(t, int64(1500000000+20*3000), assumptions.timeAt(2000000, 2003000))
+	require.Equal(t, int64(2003000), assumptions.heightAt(2000000, 1500000000+20*3000+19))
+	require.Equal(t, int64(1500), assumptions.sysFee(2000000, 2003000))
+}
+
+func TestClaimProjectionParams(t *testing.T) {
+	server := newTestServer(t)
+
+	response := server.call(&RPCRequest{
+		JSONRPC: "2.0",
+		Method:  "claimProjection",
+		Params:  []byte(`{"address": "` + testAddress + `"}`),
+		ID:      []byte(`1`),
+	})
+
+	require.NotNil(t, response.Error)
+	require.Equal(t, JSONRPCInvalidParams, response.Error.Code)
+
+	response = server.call(&RPCRequest{
+		JSONRPC: "2.0",
+		Method:  "claimProjection",
+		Params:  []byte(`["` + testAddress + `", "abc"]`),
+		ID:      []byte(`1`),
+	})
+
+	require.NotNil(t, response.Error)
+	require.Equal(t, JSONRPCInvalidParams, response.Error.Code)
+}
+
+func TestProjectionTarget(t *testing.T) {
+	assumptions := &ProjectionAssumptions{TipTime: 1500000000, SecondsPerBlock: 20}
+
+	height, timestamp := int64(2003000), int64(1500000000+20*3000)
+
+	target, targetTime, rpcerr := assumptions.target(&claimProjectionParams{Height: &height}, 2000000, 10000)
+	require.Nil(t, rpcerr)
+	require.Equal(t, height, target)
+	require.Equal(t, timestamp, targetTime)
+
+	target, targetTime, rpcerr = assumptions.target(&claimProjectionParams{Time: &timestamp}, 2000000, 10000)
+	require.Nil(t, rpcerr)
+	require.Equal(t, height, target)
+	require.Equal(t, timestamp, targetTime)
+
+	// targets overflowing int64 through the float estimates
+	height, timestamp = math.MaxInt64, math.MaxInt64
+
+	_, _, rpcerr = assumptions.target(&claimProjectionParams{Height: &height}, 2000000, 10000)
+	require.NotNil(t, rpcerr)
+	require.Equal(t, JSONRPCInvalidParams, rpcerr.ID)
+
+	_, _, rpcerr = assumptions.target(&claimProjectionParams{Time: &timestamp}, 2000000, 10000)
+	require.NotNil(t, rpcerr)
+	require.Equal(t, JSONRPCInvalidParams, rpcerr.ID)
+
+	// at or below the tip
+	height = 2000000
+
+	_, _, rpcerr = assumptions.target(&claimProjectionParams{Height: &height}, 2000000, 10000)
+	require.NotNil(t, rpcerr)
+	require.Equal(t, JSONRPCInvalidParams, rpcerr.ID)
+}
@@ -0,0 +1,423 @@
+package insight
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/dynamicgo/config"
+	"github.com/julienschmidt/httprouter"
+)
+
+// proxy routes
+const (
+	routeForward = iota
+	routeLocal
+	routeDenied
+)
+
+// proxyPolicy method aware routing of the proxy endpoint: methods in deny list (or not in the non empty
+// allow list) are rejected, methods mapped in local are served by extend handlers, others go to node
+type proxyPolicy struct {
+	allow map[string]bool
+	deny  map[string]bool
+	local map[string]string
+}
+
+func newProxyPolicy(cnf *config.Config) (*proxyPolicy, error) {
+
+	var allow, deny []string
+
+	local := map[string]string{
+		"getclaim": "claim",
+	}
+
+	if cnf.Has("insight.proxy_allow") {
+		if err := cnf.GetObject("insight.proxy_allow", &allow); err != nil {
+			return nil, fmt.Errorf("load insight.proxy_allow err, %s", err)
+		}
+	}
+
+	if cnf.Has("insight.proxy_deny") {
+		if err := cnf.GetObject("insight.proxy_deny", &deny); err != nil {
+			return nil, fmt.Errorf("load insight.proxy_deny err, %s", err)
+		}
+	}
+
+	if cnf.Has("insight.proxy_local") {
+		local = make(map[string]string)
+
+		if err := cnf.GetObject("insight.proxy_local", &local); err != nil {
+			return nil, fmt.Errorf("load insight.proxy_local err, %s", err)
+		}
+	}
+
+	policy := &proxyPolicy{
+		allow: make(map[string]bool),
+		deny:  make(map[string]bool),
+		local: local,
+	}
+
+	for _, method := range allow {
+		policy.allow[method] = true
+	}
+
+	for _, method := range deny {
+		policy.deny[method] = true
+	}
+
+	return policy, nil
+}
+
+// route get the route of proxy method, and the extend method name for local route.
+// Extend methods are served locally by their own name too
+func (server *Server) route(method string) (int, string) {
+	policy := server.proxyPolicy
+
+	if policy.deny[method] {
+		return routeDenied, ""
+	}
+
+	if local, ok := policy.local[method]; ok {
+		if _, ok := server.dispatch[local]; ok {
+			return routeLocal, local
+		}
+	}
+
+	if _, ok := server.dispatch[method]; ok {
+		return routeLocal, method
+	}
+
+	if len(policy.allow) > 0 && !policy.allow[method] {
+		return routeDenied, ""
+	}
+
+	return routeForward, ""
+}
+
+// serveLocal serve proxy request by local route, return nil for notification
+func (server *Server) serveLocal(request *RPCRequest, route int, local string) *RPCResponse {
+	if route == routeDenied {
+		if request.IsNotification() {
+			return nil
+		}
+
+		return newRPCError(request.ID, JSONRPCMethodDenied, fmt.Sprintf("method %s is not allowed", request.Method), &ErrorData{
+			Reason:  ReasonMethodDenied,
+			Details: map[string]interface{}{"method": request.Method},
+		})
+	}
+
+	localRequest := *request
+	localRequest.Method = local
+
+	return server.call(&localRequest)
+}
+
+// ReverseProxy method aware jsonrpc proxy handler
+func (server *Server) ReverseProxy(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	body, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		writeJSON(w, newRPCError(nil, JSONRPCParserError, "parse error", nil))
+		return
+	}
+
+	if isBatch(body) {
+		server.proxyBatch(w, r, body)
+		return
+	}
+
+	request, err := makeRPCRequest(body)
+
+	if err != nil {
+		writeJSON(w, newRPCError(nil, JSONRPCParserError, "parse error", nil))
+		return
+	}
+
+	route, local := server.route(request.Method)
+
+	if route == routeForward {
+		server.forwardCached(w, r, request, body)
+		return
+	}
+
+	response := server.serveLocal(request, route, local)
+
+	if response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, response)
+}
+
+// forward send the request body to upstream nodes as is and copy back the response
+func (server *Server) forward(w http.ResponseWriter, r *http.Request, body []byte) {
+
+	response, err := server.upstream.forward(r, body, isIdempotent(body))
+
+	if err != nil {
+		logger.ErrorF("proxy request err, %s", err)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+		return
+	}
+
+	writeUpstreamResponse(w, response)
+}
+
+func writeUpstreamResponse(w http.ResponseWriter, response *upstreamResponse) {
+	for _, key := range []string{"Content-Type", "Content-Encoding"} {
+		if value := response.Header.Get(key); value != "" {
+			w.Header().Set(key, value)
+		}
+	}
+
+	w.WriteHeader(response.StatusCode)
+
+	if _, err := w.Write(response.Body); err != nil {
+		logger.ErrorF("write response error :%s", err)
+	}
+}
+
+// forwardCached serve the request from proxy cache if hit, otherwise forward it and cache the node response
+func (server *Server) forwardCached(w http.ResponseWriter, r *http.Request, request *RPCRequest, body []byte) {
+
+	if server.proxyCache == nil {
+		server.forward(w, r, body)
+		return
+	}
+
+	if result, ok := server.proxyCache.get(request); ok {
+		w.Header().Set("X-Cache", "HIT")
+		writeJSON(w, newRPCResult(request.ID, result))
+		return
+	}
+
+	response, err := server.upstream.forward(r, body, isIdempotent(body))
+
+	if err != nil {
+		logger.ErrorF("proxy request err, %s", err)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+		return
+	}
+
+	if response.StatusCode == http.StatusOK {
+		server.proxyCache.put(request, response.Body)
+	}
+
+	w.Header().Set("X-Cache", "MISS")
+
+	writeUpstreamResponse(w, response)
+}
+
+// proxyBatch split batch into denied, local and forwarded requests, the forwarded ones are sent
+// to node as one sub batch, and the node responses are merged back in request order by id
+func (server *Server) proxyBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+
+	messages, rpcerr := server.decodeBatch(body)
+
+	if rpcerr != nil {
+		writeJSON(w, rpcerr)
+		return
+	}
+
+	requests := make([]*RPCRequest, len(messages))
+	responses := make([]*RPCResponse, len(messages))
+	forwards := make([]int, 0, len(messages))
+
+	for i, message := range messages {
+		request, err := makeRPCRequest(message)
+
+		if err != nil {
+			responses[i] = newRPCError(nil, JSONRPCInvalidRequest, "invalid request", nil)
+			continue
+		}
+
+		route, local := server.route(request.Method)
+
+		switch route {
+		case routeForward:
+			forwards = append(forwards, i)
+		case routeDenied:
+			responses[i] = server.serveLocal(request, route, local)
+		default:
+			localRequest := *request
+			localRequest.Method = local
+			requests[i] = &localRequest
+		}
+	}
+
+	if len(forwards) == len(messages) {
+		server.forward(w, r, body)
+		return
+	}
+
+	server.callAll(requests, responses)
+
+	results := make([]json.RawMessage, len(messages))
+
+	for i, response := range responses {
+		if response == nil {
+			continue
+		}
+
+		data, err := json.Marshal(response)
+
+		if err != nil {
+			logger.ErrorF("marshal response error :%s", err)
+			data, _ = json.Marshal(newRPCError(response.ID, JSONRPCInnerError, "internal error", nil))
+		}
+
+		results[i] = data
+	}
+
+	var unmatched []json.RawMessage
+
+	if len(forwards) > 0 {
+		unmatched = server.forwardBatch(r, messages, forwards, results)
+	}
+
+	merged := make([]json.RawMessage, 0, len(results)+len(unmatched))
+
+	for _, result := range results {
+		if result != nil {
+			merged = append(merged, result)
+		}
+	}
+
+	merged = append(merged, unmatched...)
+
+	if len(merged) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, merged)
+}
+
+// forwardBatch forward the sub batch messages[forwards] to node and fill results by response id,
+// the node responses which match no request are returned
+func (server *Server) forwardBatch(r *http.Request, messages []json.RawMessage, forwards []int, results []json.RawMessage) []json.RawMessage {
+
+	sub := make([]json.RawMessage, 0, len(forwards))
+
+	for _, i := range forwards {
+		sub = append(sub, messages[i])
+	}
+
+	body, _ := json.Marshal(sub)
+
+	fail := func(message string) {
+		for _, i := range forwards {
+			request, _ := makeRPCRequest(messages[i])
+
+			if request.IsNotification() {
+				continue
+			}
+
+			results[i], _ = json.Marshal(newRPCError(request.ID, JSONRPCInnerError, message, &ErrorData{
+				Reason: ReasonUpstreamUnavailable,
+			}))
+		}
+	}
+
+	response, err := server.upstream.forward(r, body, isIdempotent(body))
+
+	if err != nil {
+		logger.ErrorF("proxy batch request err, %s", err)
+		fail("upstream unavailable")
+		return nil
+	}
+
+	var nodeResponses []json.RawMessage
+
+	if err := json.Unmarshal(response.Body, &nodeResponses); err != nil {
+		logger.ErrorF("unmarshal upstream batch response err, %s", err)
+		fail("invalid upstream response")
+		return nil
+	}
+
+	byID := make(map[string][]int)
+
+	for j, nodeResponse := range nodeResponses {
+		var envelope struct {
+			ID json.RawMessage `json:"id"`
+		}
+
+		json.Unmarshal(nodeResponse, &envelope)
+
+		key := compactID(envelope.ID)
+
+		byID[key] = append(byID[key], j)
+	}
+
+	matched := make([]bool, len(nodeResponses))
+
+	for _, i := range forwards {
+		request, _ := makeRPCRequest(messages[i])
+
+		if request.IsNotification() {
+			continue
+		}
+
+		key := compactID(request.ID)
+
+		if candidates := byID[key]; len(candidates) > 0 {
+			results[i] = nodeResponses[candidates[0]]
+			matched[candidates[0]] = true
+			byID[key] = candidates[1:]
+		}
+	}
+
+	var unmatched []json.RawMessage
+
+	for j, nodeResponse := range nodeResponses {
+		if !matched[j] {
+			unmatched = append(unmatched, nodeResponse)
+		}
+	}
+
+	return unmatched
+}
+
+func compactID(id json.RawMessage) string {
+	var buff bytes.Buffer
+
+	if err := json.Compact(&buff, id); err != nil {
+		return string(id)
+	}
+
+	return buff.String()
+}
+
+// isIdempotent check if the proxy request (or every request in batch) can be retried on another node,
+// unparsable request is treated as non idempotent
+func isIdempotent(body []byte) bool {
+	var requests []*RPCRequest
+
+	if isBatch(body) {
+		if err := json.Unmarshal(body, &requests); err != nil {
+			return false
+		}
+	} else {
+		request := &RPCRequest{}
+
+		if err := json.Unmarshal(body, request); err != nil {
+			return false
+		}
+
+		requests = append(requests, request)
+	}
+
+	for _, request := range requests {
+		if request == nil || nonIdempotentMethods[request.Method] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,129 @@
+package insight
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dynamicgo/config"
+	"github.com/stretchr/testify/require"
+)
+
+type echoParams struct {
+	Value string `json:"value"`
+}
+
+func newTestProxy(t *testing.T) (*Server, *httptest.Server) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		if !isBatch(body) {
+			request, _ := makeRPCRequest(body)
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"node %s"}`, request.ID, request.Method)
+			return
+		}
+
+		var requests []*RPCRequest
+
+		json.Unmarshal(body, &requests)
+
+		responses := make([]string, 0)
+
+		// answer in reverse order, the proxy must restore request order
+		for i := len(requests) - 1; i >= 0; i-- {
+			if !requests[i].IsNotification() {
+				responses = append(responses, fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":"node %s"}`, requests[i].ID, requests[i].Method))
+			}
+		}
+
+		fmt.Fprintf(w, "[%s]", strings.Join(responses, ","))
+	}))
+
+	server := newTestServer(t)
+
+	server.register("echo", echoParams{}, func(params interface{}) (interface{}, *JSONRPCError) {
+		return "local " + params.(*echoParams).Value, nil
+	})
+
+	server.register("nothing", echoParams{}, func(params interface{}) (interface{}, *JSONRPCError) {
+		return nil, nil
+	})
+
+	cnf, err := config.New([]byte(fmt.Sprintf(`{
+		"insight":{
+			"upstream":{"nodes":["%s"]},
+			"proxy_deny":["dumpprivkey"],
+			"proxy_local":{"getecho":"echo","getnothing":"nothing"}
+		}
+	}`, node.URL)))
+
+	require.NoError(t, err)
+
+	server.upstream, err = newUpstreamPool(cnf)
+
+	require.NoError(t, err)
+
+	server.proxyPolicy, err = newProxyPolicy(cnf)
+
+	require.NoError(t, err)
+
+	return server, node
+}
+
+func doProxy(server *Server, body string) string {
+	w := httptest.NewRecorder()
+
+	server.ReverseProxy(w, httptest.NewRequest("POST", "/", strings.NewReader(body)), nil)
+
+	return w.Body.String()
+}
+
+func TestProxyRoute(t *testing.T) {
+	server, node := newTestProxy(t)
+	defer node.Close()
+
+	require.JSONEq(t,
+		`{"jsonrpc":"2.0","id":1,"result":"node getblockcount"}`,
+		doProxy(server, `{"jsonrpc":"2.0","method":"getblockcount","id":1}`),
+	)
+
+	require.JSONEq(t,
+		`{"jsonrpc":"2.0","id":1,"result":"local a"}`,
+		doProxy(server, `{"jsonrpc":"2.0","method":"getecho","params":["a"],"id":1}`),
+	)
+
+	require.JSONEq(t,
+		`{"jsonrpc":"2.0","id":1,"result":null}`,
+		doProxy(server, `{"jsonrpc":"2.0","method":"getnothing","params":["a"],"id":1}`),
+	)
+
+	var response RPCResponse
+
+	require.NoError(t, json.Unmarshal([]byte(doProxy(server, `{"jsonrpc":"2.0","method":"dumpprivkey","params":["a"],"id":1}`)), &response))
+	require.Equal(t, JSONRPCMethodDenied, response.Error.Code)
+}
+
+func TestProxyBatch(t *testing.T) {
+	server, node := newTestProxy(t)
+	defer node.Close()
+
+	require.JSONEq(t,
+		`[
+			{"jsonrpc":"2.0","id":1,"result":"node getblock"},
+			{"jsonrpc":"2.0","id":2,"result":"local b"},
+			{"jsonrpc":"2.0","id":3,"error":{"code":-32006,"message":"method dumpprivkey is not allowed","data":{"reason":"method_denied","details":{"method":"dumpprivkey"}}}},
+			{"jsonrpc":"2.0","id":"4","result":"node getblockcount"}
+		]`,
+		doProxy(server, `[
+			{"jsonrpc":"2.0","method":"getblock","params":[1],"id":1},
+			{"jsonrpc":"2.0","method":"getecho","params":{"value":"b"},"id":2},
+			{"jsonrpc":"2.0","method":"dumpprivkey","id":3},
+			{"jsonrpc":"2.0","method":"getbestblockhash"},
+			{"jsonrpc":"2.0","method":"getblockcount","id":"4"}
+		]`),
+	)
+}
@@ -0,0 +1,180 @@
+package insight
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/dynamicgo/config"
+	"github.com/go-redis/redis"
+)
+
+// proxy cache metrics, published by expvar
+var (
+	proxyCacheHits   = expvar.NewInt("proxy_cache_hits")
+	proxyCacheMisses = expvar.NewInt("proxy_cache_misses")
+	proxyCacheStores = expvar.NewInt("proxy_cache_stores")
+)
+
+// proxyCacheVersion cache key version, bump it when cached value format changes
+const proxyCacheVersion = "v1"
+
+// cacheRule decide if node response of the method is immutable. Index rule checks the block index
+// param is deep enough, confirmations rule checks the confirmations of verbose result, the response of
+// always rule is cached with the short ttl as it may change slowly (e.g. asset available amount)
+type cacheRule struct {
+	indexParam    bool
+	confirmations bool
+	always        bool
+}
+
+var cacheRules = map[string]*cacheRule{
+	"getblock":          {indexParam: true, confirmations: true},
+	"getblockhash":      {indexParam: true},
+	"getblocksysfee":    {indexParam: true},
+	"getrawtransaction": {confirmations: true},
+	"getassetstate":     {always: true},
+}
+
+// proxyCache redis cache of immutable node responses in front of the proxy
+type proxyCache struct {
+	redisclient  *redis.Client
+	prefix       string
+	depth        int64
+	ttl          time.Duration
+	shortTTL     time.Duration
+	maxEntrySize int
+	height       func() int64
+}
+
+func newProxyCache(cnf *config.Config, client *redis.Client, height func() int64) *proxyCache {
+	return &proxyCache{
+		redisclient:  client,
+		prefix:       cnf.GetString("insight.proxy_cache.prefix", "insight:proxy"),
+		depth:        cnf.GetInt64("insight.proxy_cache.depth", 6),
+		ttl:          time.Second * cnf.GetDuration("insight.proxy_cache.ttl", 24*3600),
+		shortTTL:     time.Second * cnf.GetDuration("insight.proxy_cache.short_ttl", 600),
+		maxEntrySize: int(cnf.GetInt64("insight.proxy_cache.max_entry_size", 256*1024)),
+		height:       height,
+	}
+}
+
+func (cache *proxyCache) key(request *RPCRequest) string {
+	var params bytes.Buffer
+
+	if len(request.Params) > 0 {
+		if err := json.Compact(&params, request.Params); err != nil {
+			params.Reset()
+			params.Write(request.Params)
+		}
+	}
+
+	hash := sha1.Sum(params.Bytes())
+
+	return fmt.Sprintf("%s:%s:%s:%s", cache.prefix, proxyCacheVersion, request.Method, hex.EncodeToString(hash[:]))
+}
+
+// get cached result of the request
+func (cache *proxyCache) get(request *RPCRequest) (json.RawMessage, bool) {
+
+	if _, ok := cacheRules[request.Method]; !ok || request.IsNotification() {
+		return nil, false
+	}
+
+	val, err := cache.redisclient.Get(cache.key(request)).Bytes()
+
+	if err != nil {
+		if err != redis.Nil {
+			logger.ErrorF("get proxy cache %s err, %s", request.Method, err)
+		}
+
+		proxyCacheMisses.Add(1)
+
+		return nil, false
+	}
+
+	proxyCacheHits.Add(1)
+
+	return val, true
+}
+
+// put cache the node response of the request if it is cacheable
+func (cache *proxyCache) put(request *RPCRequest, body []byte) {
+
+	if len(body) > cache.maxEntrySize {
+		return
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return
+	}
+
+	if response.Error != nil || response.Result == nil || bytes.Equal(response.Result, []byte("null")) {
+		return
+	}
+
+	if !cache.cacheable(request, response.Result) {
+		return
+	}
+
+	ttl := cache.ttl
+
+	if cacheRules[request.Method].always {
+		ttl = cache.shortTTL
+	}
+
+	if err := cache.redisclient.Set(cache.key(request), []byte(response.Result), ttl).Err(); err != nil {
+		logger.ErrorF("set proxy cache %s err, %s", request.Method, err)
+		return
+	}
+
+	proxyCacheStores.Add(1)
+}
+
+// cacheable check the confirmation depth rule of the method
+func (cache *proxyCache) cacheable(request *RPCRequest, result json.RawMessage) bool {
+	rule, ok := cacheRules[request.Method]
+
+	if !ok {
+		return false
+	}
+
+	if rule.always {
+		return true
+	}
+
+	var params []json.RawMessage
+
+	json.Unmarshal(request.Params, &params)
+
+	if rule.indexParam && len(params) > 0 {
+		var index int64
+
+		if err := json.Unmarshal(params[0], &index); err == nil {
+			blockCount := cache.height()
+
+			return blockCount > 0 && index < blockCount-cache.depth
+		}
+	}
+
+	if rule.confirmations {
+		var verbose struct {
+			Confirmations int64 `json:"confirmations"`
+		}
+
+		if err := json.Unmarshal(result, &verbose); err == nil {
+			return verbose.Confirmations >= cache.depth
+		}
+	}
+
+	return false
+}
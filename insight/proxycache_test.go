@@ -0,0 +1,48 @@
+package insight
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyCacheable(t *testing.T) {
+	cache := &proxyCache{
+		prefix: "test",
+		depth:  6,
+		height: func() int64 { return 100 },
+	}
+
+	cacheable := func(method, params, result string) bool {
+		return cache.cacheable(&RPCRequest{Method: method, Params: []byte(params)}, []byte(result))
+	}
+
+	require.True(t, cacheable("getblock", `[10, 1]`, `{"index":10}`))
+	require.True(t, cacheable("getblockhash", `[93]`, `"0x01"`))
+	require.False(t, cacheable("getblockhash", `[94]`, `"0x01"`))
+	require.True(t, cacheable("getblock", `["0x01", 1]`, `{"confirmations":6}`))
+	require.False(t, cacheable("getblock", `["0x01", 1]`, `{"confirmations":5}`))
+	require.False(t, cacheable("getblock", `["0x01"]`, `"00aa"`))
+	require.True(t, cacheable("getrawtransaction", `["0x01", 1]`, `{"confirmations":100}`))
+	require.False(t, cacheable("getrawtransaction", `["0x01", 1]`, `{"txid":"0x01"}`))
+	require.True(t, cacheable("getassetstate", `["0x01"]`, `{}`))
+	require.False(t, cacheable("getblockcount", `[]`, `100`))
+
+	cache.height = func() int64 { return 0 }
+
+	require.False(t, cacheable("getblock", `[10, 1]`, `{"index":10}`))
+}
+
+func TestProxyCacheKey(t *testing.T) {
+	cache := &proxyCache{prefix: "test"}
+
+	require.Equal(t,
+		cache.key(&RPCRequest{Method: "getblock", Params: []byte(`[1, 1]`)}),
+		cache.key(&RPCRequest{Method: "getblock", Params: []byte(`[1,1]`)}),
+	)
+
+	require.NotEqual(t,
+		cache.key(&RPCRequest{Method: "getblock", Params: []byte(`[1]`)}),
+		cache.key(&RPCRequest{Method: "getblockhash", Params: []byte(`[1]`)}),
+	)
+}
@@ -0,0 +1,37 @@
+package insight
+
+import (
+	"encoding/json"
+	"runtime/debug"
+)
+
+// invoke bind params and call the method handler, a panic raised by either of them is
+// recovered and reported as inner error with a correlation id pointing to the logged stack
+func (m *method) invoke(name string, raw json.RawMessage) (result interface{}, rpcerr *JSONRPCError) {
+
+	defer func() {
+		if e := recover(); e != nil {
+			correlationID := newCorrelationID()
+
+			logger.ErrorF("[%s] extend method %s panic: %v\n%s", correlationID, name, e, debug.Stack())
+
+			result = nil
+			rpcerr = &JSONRPCError{
+				ID:      JSONRPCInnerError,
+				Message: "internal error",
+				Data: &ErrorData{
+					Reason:        ReasonInternal,
+					CorrelationID: correlationID,
+				},
+			}
+		}
+	}()
+
+	params, rpcerr := m.bind(raw)
+
+	if rpcerr != nil {
+		return nil, rpcerr
+	}
+
+	return m.handler(params)
+}
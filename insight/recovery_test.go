@@ -0,0 +1,56 @@
+package insight
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type panicParams struct {
+	Index int `json:"index"`
+}
+
+func TestRecoverHandlerPanic(t *testing.T) {
+	server := newTestServer(t)
+
+	server.register("panic", panicParams{}, func(params interface{}) (interface{}, *JSONRPCError) {
+		blocks := make([]int, 0)
+
+		return blocks[params.(*panicParams).Index], nil
+	})
+
+	response := server.call(&RPCRequest{
+		JSONRPC: "2.0",
+		Method:  "panic",
+		Params:  []byte(`[1]`),
+		ID:      []byte(`1`),
+	})
+
+	require.NotNil(t, response.Error)
+	require.Equal(t, JSONRPCInnerError, response.Error.Code)
+
+	data, ok := response.Error.Data.(*ErrorData)
+
+	require.True(t, ok)
+	require.Equal(t, ReasonInternal, data.Reason)
+	require.NotEmpty(t, data.CorrelationID)
+}
+
+func TestAppErrorData(t *testing.T) {
+	err := asJSONRPCError(newAppError(JSONRPCIndexerBehind, map[string]interface{}{"start": 1}, "no blocks"))
+
+	require.Equal(t, JSONRPCIndexerBehind, err.ID)
+	require.Equal(t, ReasonIndexerBehind, err.Data.(*ErrorData).Reason)
+	require.Equal(t, 1, err.Data.(*ErrorData).Details["start"])
+}
+
+func TestDatastoreErrorData(t *testing.T) {
+	OpenLogger()
+
+	err := asJSONRPCError(newAppError(JSONRPCDatastoreUnavailable, nil, "get sys_fee amount of %d err, %s", 1, "pq: password authentication failed"))
+
+	require.Equal(t, JSONRPCDatastoreUnavailable, err.ID)
+	require.Equal(t, "datastore unavailable", err.Message)
+	require.Equal(t, ReasonDatastoreUnavailable, err.Data.(*ErrorData).Reason)
+	require.NotEmpty(t, err.Data.(*ErrorData).CorrelationID)
+}
@@ -1,14 +1,14 @@
 package insight
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
-	"strconv"
-	"sync"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/dynamicgo/config"
@@ -19,7 +19,6 @@ import (
 	"github.com/inwecrypto/neodb"
 	"github.com/inwecrypto/neogo/rpc"
 	"github.com/julienschmidt/httprouter"
-	"github.com/ybbus/jsonrpc"
 )
 
 var logger slf4go.Logger
@@ -29,11 +28,13 @@ func OpenLogger() {
 	logger = slf4go.Get("neo-insight")
 }
 
-type handler func(params []interface{}) (interface{}, *JSONRPCError)
+type handler func(params interface{}) (interface{}, *JSONRPCError)
 
 type syncAddress struct {
-	Address string
-	Times   int
+	Address   string
+	Times     int
+	token     string
+	recovered bool
 }
 
 func (address *syncAddress) String() string {
@@ -42,17 +43,31 @@ func (address *syncAddress) String() string {
 
 // Server insight api jsonrpc 2.0 server
 type Server struct {
-	mutex        sync.Mutex
-	cnf          *config.Config
-	router       *httprouter.Router
-	remote       *url.URL
-	dispatch     map[string]handler
-	engine       *xorm.Engine
-	redisclient  *redis.Client
-	syncChan     chan *syncAddress
-	syncFlag     map[string]string
-	syncTimes    int
-	syncDuration time.Duration
+	cnf           *config.Config
+	router        *httprouter.Router
+	upstream      *upstreamPool
+	proxyPolicy   *proxyPolicy
+	proxyCache    *proxyCache
+	sysfee        *sysFeeIndex
+	network       *claim.Params
+	verifier      *verifier
+	checkpoints   *checkpointStore
+	dispatch      map[string]*method
+	engine        *xorm.Engine
+	claimCache    ClaimCache
+	syncChan      chan *syncAddress
+	syncStore     syncStore
+	syncLease     time.Duration
+	syncPoll      time.Duration
+	watchInterval time.Duration
+	syncWorkers   int
+	syncTimeout   time.Duration
+	syncAttempts  int
+	syncRetry     time.Duration
+	claimDeadline time.Duration
+	claimFlights  *claimFlights
+	batchLimit    int
+	batchWorkers  int
 }
 
 type loggerHandler struct {
@@ -67,7 +82,25 @@ func (l *loggerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // NewServer create new server
 func NewServer(cnf *config.Config) (*Server, error) {
 
-	remote, err := url.Parse(cnf.GetString("insight.neo", "http://xxxxxx:10332"))
+	upstream, err := newUpstreamPool(cnf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	proxyPolicy, err := newProxyPolicy(cnf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	network, err := loadNetwork(cnf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := newVerifier(cnf, upstream)
 
 	if err != nil {
 		return nil, err
@@ -91,23 +124,65 @@ func NewServer(cnf *config.Config) (*Server, error) {
 		return nil, err
 	}
 
-	client := redis.NewClient(&redis.Options{
-		Addr:     cnf.GetString("insight.redis.address", "localhost:6379"),
-		Password: cnf.GetString("insight.redis.password", "xxxxxx"), // no password set
-		DB:       int(cnf.GetInt64("insight.redis.db", 1)),          // use default DB
-	})
+	// redis is only connected if a store uses it
+	var client *redis.Client
+
+	redisClient := func() *redis.Client {
+		if client == nil {
+			client = redis.NewClient(&redis.Options{
+				Addr:     cnf.GetString("insight.redis.address", "localhost:6379"),
+				Password: cnf.GetString("insight.redis.password", "xxxxxx"), // no password set
+				DB:       int(cnf.GetInt64("insight.redis.db", 1)),          // use default DB
+			})
+		}
+
+		return client
+	}
+
+	syncStore, err := newSyncStore(cnf, redisClient)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var cache *proxyCache
+
+	if cnf.GetBool("insight.proxy_cache.enable", redisDefault(cnf)) {
+		cache = newProxyCache(cnf, redisClient(), upstream.bestBlockCount)
+	}
 
 	server := &Server{
-		cnf:          cnf,
-		router:       httprouter.New(),
-		remote:       remote,
-		dispatch:     make(map[string]handler),
-		engine:       engine,
-		redisclient:  client,
-		syncChan:     make(chan *syncAddress, cnf.GetInt64("insight.sync_chan_length", 1024)),
-		syncFlag:     make(map[string]string),
-		syncTimes:    int(cnf.GetInt64("insight.sync_times", 20)),
-		syncDuration: time.Second * cnf.GetDuration("insight.sync_duration", 4),
+		cnf:           cnf,
+		router:        httprouter.New(),
+		upstream:      upstream,
+		proxyPolicy:   proxyPolicy,
+		proxyCache:    cache,
+		sysfee:        newSysFeeIndex(cnf, engine),
+		network:       network,
+		verifier:      verifier,
+		dispatch:      make(map[string]*method),
+		engine:        engine,
+		syncChan:      make(chan *syncAddress, cnf.GetInt64("insight.sync_chan_length", 1024)),
+		syncStore:     syncStore,
+		watchInterval: time.Second * cnf.GetDuration("insight.watch.interval", 2),
+		syncWorkers:   int(cnf.GetInt64("insight.sync_concurrency", 8)),
+		syncTimeout:   time.Second * cnf.GetDuration("insight.sync_timeout", 60),
+		syncAttempts:  int(cnf.GetInt64("insight.sync_queue.attempts", 3)),
+		syncRetry:     time.Second * cnf.GetDuration("insight.sync_queue.retry_delay", 600),
+		claimDeadline: time.Millisecond * cnf.GetDuration("insight.claim.sync_deadline", 0),
+		claimFlights:  newClaimFlights(int(cnf.GetInt64("insight.claim.sync_concurrency", 4))),
+		syncLease:     time.Second * cnf.GetDuration("insight.sync_queue.lease", 120),
+		syncPoll:      time.Millisecond * cnf.GetDuration("insight.sync_queue.poll", 500),
+		batchLimit:    int(cnf.GetInt64("insight.batch_limit", 100)),
+		batchWorkers:  int(cnf.GetInt64("insight.batch_concurrency", 8)),
+	}
+
+	if server.claimCache, err = newClaimCache(cnf, redisClient, server.doGetClaim); err != nil {
+		return nil, err
+	}
+
+	if cnf.GetBool("insight.checkpoint.enable", redisDefault(cnf)) {
+		server.checkpoints = newCheckpointStore(cnf, redisClient())
 	}
 
 	return server, nil
@@ -133,26 +208,17 @@ func openDB(cnf *config.Config) (*sql.DB, error) {
 	return db, nil
 }
 
-func makeJSONRPCError(w http.ResponseWriter, id uint, code int, message string, data interface{}) {
-	response := &jsonrpc.RPCResponse{
-		JSONRPC: "2.0",
-		ID:      id,
-		Error: &jsonrpc.RPCError{
-			Code:    code,
-			Message: message,
-			Data:    data,
-		},
-	}
+func writeJSON(w http.ResponseWriter, response interface{}) {
 
 	jsonresponse, err := json.Marshal(response)
 
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, "server internal error", http.StatusInternalServerError)
 		logger.ErrorF("marshal response error :%s", err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(200)
 
 	if _, err := w.Write(jsonresponse); err != nil {
@@ -160,9 +226,9 @@ func makeJSONRPCError(w http.ResponseWriter, id uint, code int, message string,
 	}
 }
 
-func makeRPCRequest(r *http.Request) (*jsonrpc.RPCRequest, error) {
-	request := jsonrpc.RPCRequest{}
-	decoder := json.NewDecoder(r.Body)
+func makeRPCRequest(data []byte) (*RPCRequest, error) {
+	request := RPCRequest{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
 	decoder.UseNumber()
 
 	err := decoder.Decode(&request)
@@ -174,40 +240,30 @@ func makeRPCRequest(r *http.Request) (*jsonrpc.RPCRequest, error) {
 	return &request, nil
 }
 
-func makeJSONRPCResponse(w http.ResponseWriter, id uint, data interface{}) {
-	response := &jsonrpc.RPCResponse{
-		JSONRPC: "2.0",
-		ID:      id,
-		Result:  data,
-	}
+// Run insight server
+func (server *Server) Run() {
 
-	jsonresponse, err := json.Marshal(response)
+	server.router.POST(server.cnf.GetString("insight.extend", "/extend"), server.DipsatchJSONRPC)
 
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		http.Error(w, "server internal error", http.StatusInternalServerError)
-		logger.ErrorF("marshal response error :%s", err)
-		return
-	}
+	server.router.POST(server.cnf.GetString("insight.proxy", "/"), server.ReverseProxy)
 
-	w.WriteHeader(200)
+	server.router.GET(server.cnf.GetString("insight.openrpc", "/openrpc.json"), server.ServeOpenRPC)
 
-	if _, err := w.Write(jsonresponse); err != nil {
-		logger.ErrorF("write response error :%s", err)
-	}
-}
+	server.registerMethods()
 
-// Run insight server
-func (server *Server) Run() {
+	server.runSyncWorkers()
 
-	server.router.POST(server.cnf.GetString("insight.extend", "/extend"), server.DipsatchJSONRPC)
+	go server.watchTip()
 
-	server.router.POST(server.cnf.GetString("insight.proxy", "/"), server.ReverseProxy)
+	go server.upstream.healthCheck()
+
+	go server.sysfee.Run()
 
-	server.dispatch["balance"] = server.getBalance
-	server.dispatch["claim"] = server.getClaim
+	go server.runAdmin()
 
-	go server.syncCached()
+	if server.verifier.interval > 0 {
+		go server.verifyLoop()
+	}
 
 	logger.Fatal(http.ListenAndServe(
 		server.cnf.GetString("insight.listen", ":10332"),
@@ -217,136 +273,168 @@ func (server *Server) Run() {
 	))
 }
 
-func (server *Server) syncCached() {
-
-	for address := range server.syncChan {
-
-		logger.DebugF("sync address claimed utxos %s", address)
-
-		startTime := time.Now()
-
-		unclaimed, err := server.doGetClaim(address.Address)
-
-		claimTimes := time.Now().Sub(startTime)
-
-		if err != nil {
-			logger.ErrorF("sync claim for address %s err, %s", address, err)
-			continue
-		}
-
-		logger.DebugF("[doGetClaim] claim %s spent times %s", address.Address, claimTimes)
-
-		data, err := json.Marshal(unclaimed)
+// BackfillSysFee build the cumulative sys_fee index from neo_block, it resumes from the last indexed block
+func (server *Server) BackfillSysFee() error {
+	height, err := server.sysfee.Sync()
 
-		if err != nil {
-			logger.ErrorF("sync claim for address %s err, %s", address, err)
-			continue
-		}
+	logger.InfoF("sys_fee index backfilled to %d", height)
 
-		err = server.redisclient.Set(address.Address, data, time.Hour*24).Err()
+	return err
+}
 
-		if err != nil {
-			logger.ErrorF("cached claim for address %s err, %s", address, err)
-			continue
-		}
+func (server *Server) registerMethods() {
+	server.register("balance", balanceParams{}, server.getBalance).
+		describe(
+			"get address's unspent utxos of asset", []*rpc.UTXO{},
+			JSONRPCInvalidAddress, JSONRPCUnknownAsset, JSONRPCDatastoreUnavailable,
+		)
+
+	server.register("claim", claimParams{}, server.getClaim).
+		describe("get address's cached unclaimed gas with its freshness", &ClaimResult{}, JSONRPCInvalidAddress)
+
+	server.register("claimDetail", claimParams{}, server.getClaimDetail).
+		describe(
+			"calc address's unclaimed gas with the breakdown of each utxo", &ClaimDetail{},
+			JSONRPCInvalidAddress, JSONRPCIndexerBehind, JSONRPCIncompleteBlocks, JSONRPCDatastoreUnavailable,
+		)
+
+	server.register("claimProjection", claimProjectionParams{}, server.getClaimProjection).
+		describe(
+			"project address's unavailable gas to a future block height or time", &ClaimProjection{},
+			JSONRPCInvalidAddress, JSONRPCIndexerBehind, JSONRPCIncompleteBlocks, JSONRPCDatastoreUnavailable,
+		)
+
+	server.register("buildClaimTx", buildClaimTxParams{}, server.buildClaimTx).
+		describe(
+			"build the unsigned claim transaction of address's available gas", &ClaimTransaction{},
+			JSONRPCInvalidAddress, JSONRPCIndexerBehind, JSONRPCIncompleteBlocks, JSONRPCDatastoreUnavailable, JSONRPCNothingToClaim,
+		)
+
+	server.register("planClaimTxs", planClaimTxsParams{}, server.planClaimTxs).
+		describe(
+			"split address's available gas into claim transactions within the size limits", &ClaimTxPlans{},
+			JSONRPCInvalidAddress, JSONRPCIndexerBehind, JSONRPCIncompleteBlocks, JSONRPCDatastoreUnavailable, JSONRPCNothingToClaim,
+		)
+
+	server.register("rpc.discover", discoverParams{}, server.discover).
+		describe("get the OpenRPC document of extend api", &OpenRPCDocument{})
+}
 
-		logger.DebugF(" sync address claimed utxos %s -- success", address)
+// register extend method, params is the struct the request params bind to
+func (server *Server) register(name string, params interface{}, h handler) *method {
+	m := &method{
+		params:  reflect.TypeOf(params),
+		handler: h,
+	}
 
-		address.Times--
+	server.dispatch[name] = m
 
-		if address.Times > 0 {
+	return m
+}
 
-			requeue := address
+// DipsatchJSONRPC .
+func (server *Server) DipsatchJSONRPC(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
-			syncDuration := server.syncDuration
+	logger.DebugF("call extend api :%s", r.RemoteAddr)
 
-			if claimTimes > 20*time.Second {
-				syncDuration = time.Minute * 10
-			}
+	data, err := ioutil.ReadAll(r.Body)
 
-			if syncDuration < server.syncDuration {
-				syncDuration = server.syncDuration
-			}
-
-			time.AfterFunc(syncDuration, func() {
-				logger.DebugF("requeue sync address %s", requeue)
+	if err != nil {
+		writeJSON(w, newRPCError(nil, JSONRPCParserError, "parse error", nil))
+		return
+	}
 
-				server.syncChan <- requeue
+	if isBatch(data) {
+		responses, rpcerr := server.dispatchBatch(data)
 
-				logger.DebugF("requeue sync address %s -- success", requeue)
-			})
+		if rpcerr != nil {
+			writeJSON(w, rpcerr)
+			return
+		}
 
-		} else {
-			logger.DebugF("delete sync address %s", address)
-			server.removeAddress(address.Address)
-			logger.DebugF("delete sync address %s -- success", address)
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
 		}
-	}
 
-}
+		writeJSON(w, responses)
+		return
+	}
 
-func (server *Server) removeAddress(address string) {
-	server.mutex.Lock()
-	defer server.mutex.Unlock()
-	delete(server.syncFlag, address)
+	request, err := makeRPCRequest(data)
 
-}
+	if err != nil {
+		writeJSON(w, newRPCError(nil, JSONRPCParserError, "parse error", nil))
+		return
+	}
 
-// ReverseProxy reverse proxy handler
-func (server *Server) ReverseProxy(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	response := server.call(request)
 
-	reverseProxy := httputil.NewSingleHostReverseProxy(server.remote)
+	if response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 
-	reverseProxy.ServeHTTP(w, r)
+	writeJSON(w, response)
 }
 
-// DipsatchJSONRPC .
-func (server *Server) DipsatchJSONRPC(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-
-	logger.DebugF("call extend api :%s", r.RemoteAddr)
-
-	request, err := makeRPCRequest(r)
+// call dispatch one jsonrpc request, return nil if the request is a notification
+func (server *Server) call(request *RPCRequest) *RPCResponse {
 
-	if err != nil {
-		makeJSONRPCError(w, 0, JSONRPCParserError, "parse error", nil)
-		return
+	if request.JSONRPC != "2.0" || request.Method == "" {
+		return newRPCError(request.ID, JSONRPCInvalidRequest, "invalid request", nil)
 	}
 
+	var response *RPCResponse
+
 	if method, ok := server.dispatch[request.Method]; ok {
-		result, err := method(request.Params.([]interface{}))
+
+		result, err := method.invoke(request.Method, request.Params)
 
 		if err != nil {
-			makeJSONRPCError(w, request.ID, err.ID, err.Message, result)
+			response = newRPCError(request.ID, err.ID, err.Message, err.Data)
 		} else {
-			makeJSONRPCResponse(w, request.ID, result)
+			response = newRPCResult(request.ID, result)
 		}
 
 	} else {
-		makeJSONRPCError(w, request.ID, JSONRPCMethodNotFound, fmt.Sprintf("method %s not found", request.Method), nil)
+		response = newRPCError(request.ID, JSONRPCMethodNotFound, fmt.Sprintf("method %s not found", request.Method), nil)
 	}
-}
 
-func (server *Server) getBalance(params []interface{}) (interface{}, *JSONRPCError) {
-	if len(params) < 2 {
-		return nil, errorf(JSONRPCInvalidParams, "expect address and asset parameters")
+	if request.IsNotification() {
+		return nil
 	}
 
-	address, ok := params[0].(string)
+	return response
+}
 
-	if !ok {
-		return nil, errorf(JSONRPCInvalidParams, "address parameter must be string")
+type balanceParams struct {
+	Address string `json:"address" desc:"neo address or script hash"`
+	Asset   string `json:"asset" desc:"asset id, or NEO/GAS"`
+}
+
+func (server *Server) getBalance(params interface{}) (interface{}, *JSONRPCError) {
+	address, rpcerr := normalizeAddress(params.(*balanceParams).Address)
+
+	if rpcerr != nil {
+		return nil, rpcerr
 	}
 
-	asset, ok := params[1].(string)
+	asset, rpcerr := normalizeAsset(params.(*balanceParams).Asset, server.network)
 
-	if !ok {
-		return nil, errorf(JSONRPCInvalidParams, "asset parameter must be string")
+	if rpcerr != nil {
+		return nil, rpcerr
 	}
 
 	utxos, err := server.unspent(address, asset)
 
 	if err != nil {
-		return nil, errorf(JSONRPCInnerError, "get %s balance %s err:\n\t%s", address, asset, err)
+		logger.ErrorF("get %s balance %s err:\n\t%s", address, asset, err)
+
+		return nil, appErrorf(
+			JSONRPCDatastoreUnavailable, nil,
+			"get %s balance %s err: datastore unavailable", address, asset,
+		)
 	}
 
 	return utxos, nil
@@ -385,103 +473,85 @@ func (server *Server) unspent(address string, asset string) ([]*rpc.UTXO, error)
 	return utxos, nil
 }
 
-func (server *Server) getClaim(params []interface{}) (interface{}, *JSONRPCError) {
-
-	if len(params) < 1 {
-		return nil, errorf(JSONRPCInvalidParams, "expect address and asset parameters")
-	}
+type claimParams struct {
+	Address string `json:"address" desc:"neo address or script hash"`
+}
 
-	address, ok := params[0].(string)
+func (server *Server) getClaim(params interface{}) (interface{}, *JSONRPCError) {
+	address, rpcerr := normalizeAddress(params.(*claimParams).Address)
 
-	if !ok {
-		return nil, errorf(JSONRPCInvalidParams, "address parameter must be string")
+	if rpcerr != nil {
+		return nil, rpcerr
 	}
 
-	unclaimed, ok := server.getCachedClaim(address)
-
-	if !ok {
-		unclaimed = &rpc.Unclaimed{
-			Unavailable: "0",
-			Available:   "0",
-		}
-	}
+	logger.DebugF("get claim: %s", address)
 
-	return unclaimed, nil
+	return server.claimResult(address), nil
 }
 
-func (server *Server) markAddress(address string) bool {
-	server.mutex.Lock()
-	defer server.mutex.Unlock()
-
-	flag := false
+// Asserts mainnet asset ids, the ids of the configured network are in Server's claim.Params
+const (
+	GasAssert = "0x602c79718b16e442de58778e148d0b1084e3b2dffd5de6b7b16cee7969282de7"
+	NEOAssert = "0xc56f33fc6ecfcd0c225c4ab356fee59390af8560be0e930faebe74a6daff7c9b"
+)
 
-	if _, ok := server.syncFlag[address]; !ok {
-		server.syncFlag[address] = address
+func (server *Server) unclaimed(address string) ([]*rpc.UTXO, error) {
+	tutxos := make([]*neodb.UTXO, 0)
 
-		flag = true
+	err := server.
+		engine.
+		Where(`address = ? and asset = ? and claimed = FALSE`, address, server.network.NEOAsset).
+		Find(&tutxos)
 
-		logger.DebugF("queued claim task: %s", address)
+	if err != nil {
+		return nil, err
 	}
 
-	return flag
+	return toRPCUTXOs(tutxos), nil
 }
 
-func (server *Server) getCachedClaim(address string) (unclaimed *rpc.Unclaimed, ok bool) {
+// claimedUTXOs the claimed utxos of address among claims
+func (server *Server) claimedUTXOs(address string, claims []*claim.ReferenceClaim) ([]*rpc.UTXO, error) {
 
-	logger.DebugF("get claim: %s", address)
-
-	flag := server.markAddress(address)
-
-	if flag {
-		server.syncChan <- &syncAddress{
-			Address: address,
-			Times:   server.syncTimes,
-		}
+	if len(claims) == 0 {
+		return nil, nil
 	}
 
-	val, err := server.redisclient.Get(address).Result()
+	keys := make(map[string]bool, len(claims))
+	txids := make([]interface{}, 0, 2*len(claims))
 
-	if err == redis.Nil {
-		return nil, false
-	}
+	for _, ref := range claims {
+		keys[claim.UTXOKey(ref.TxID, ref.N)] = true
 
-	if err != nil {
-		logger.DebugF("get cached claim for address %s err , %s", address, err)
-		return nil, false
-	}
-
-	err = json.Unmarshal([]byte(val), &unclaimed)
+		txid := strings.TrimPrefix(strings.ToLower(ref.TxID), "0x")
 
-	if err != nil {
-		logger.DebugF("get cachde claim for address %s err , %s", address, err)
-		return nil, false
+		txids = append(txids, txid, "0x"+txid)
 	}
 
-	ok = true
-
-	logger.DebugF("get cached claim for address %s -- success", address)
-
-	return
-}
-
-// Asserts .
-const (
-	GasAssert = "0x602c79718b16e442de58778e148d0b1084e3b2dffd5de6b7b16cee7969282de7"
-	NEOAssert = "0xc56f33fc6ecfcd0c225c4ab356fee59390af8560be0e930faebe74a6daff7c9b"
-)
-
-func (server *Server) unclaimed(address string) ([]*rpc.UTXO, error) {
 	tutxos := make([]*neodb.UTXO, 0)
 
 	err := server.
 		engine.
-		Where(`address = ? and asset = ? and claimed = FALSE`, address, NEOAssert).
+		Where(`address = ? and asset = ? and claimed = TRUE`, address, server.network.NEOAsset).
+		In("tx", txids...).
 		Find(&tutxos)
 
 	if err != nil {
 		return nil, err
 	}
 
+	claimed := make([]*neodb.UTXO, 0, len(tutxos))
+
+	for _, t := range tutxos {
+		if keys[claim.UTXOKey(t.TX, t.N)] {
+			claimed = append(claimed, t)
+		}
+	}
+
+	return toRPCUTXOs(claimed), nil
+}
+
+func toRPCUTXOs(tutxos []*neodb.UTXO) []*rpc.UTXO {
 	utxos := make([]*rpc.UTXO, 0)
 
 	for _, t := range tutxos {
@@ -499,121 +569,140 @@ func (server *Server) unclaimed(address string) ([]*rpc.UTXO, error) {
 		})
 	}
 
-	return utxos, nil
-
+	return utxos
 }
 
-func (server *Server) getBlocksFee(start int64, end int64) (float64, int64, error) {
+func (server *Server) doGetClaim(address string) (*CachedClaim, error) {
 
-	var rows []map[string]string
-	var err error
+	logger.DebugF("[doGetClaim]start get claim :%s", address)
 
-	if end == -1 {
-		rows, err = server.engine.QueryString(`select sum(sys_fee), max(block) from neo_block where block >= ?`, start)
-	} else {
-		rows, err = server.engine.QueryString(`select sum(sys_fee), max(block) from neo_block where block >= ? and block < ?`, start, end)
-	}
+	tip, details, err := server.claimDetails(address)
 
 	if err != nil {
-		return 0, end, err
+		return nil, err
 	}
 
-	if len(rows) == 0 {
-		return 0, end, nil
-	}
+	unavailable, available := claim.SumDetails(details)
 
-	sum, err := strconv.ParseFloat(rows[0]["sum"], 32)
+	claims := make([]*rpc.UTXO, 0)
 
-	if err != nil {
-		return 0, end, nil
+	for _, detail := range details {
+		if detail.Available {
+			claims = append(claims, detail.UTXO)
+		}
 	}
 
-	max, err := strconv.ParseFloat(rows[0]["max"], 32)
-
-	if err != nil {
-		return 0, end, nil
+	cached := &CachedClaim{
+		Unclaimed: &rpc.Unclaimed{
+			Available:   claim.FormatFixed8(available),
+			Unavailable: claim.FormatFixed8(unavailable),
+			Claims:      claims,
+		},
+		Height:   tip,
+		Weight:   server.network.Weight(details),
+		Computed: time.Now(),
 	}
 
-	return sum, int64(max), nil
-}
-
-func (server *Server) getBlocks(start int64, end int64) ([]*neodb.Block, error) {
-
-	blocks := make([]*neodb.Block, 0)
-
-	if end == -1 {
-		err := server.engine.Where(`block >= ?`, start).Find(&blocks)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		if err := server.engine.Where(`block >= ? and block <= ?`, start, end).Cols("block", "sys_fee").Find(&blocks); err != nil {
-			return nil, err
-		}
-	}
+	logger.DebugF(
+		"[doGetClaim]finish get claim: %s available: %s unavailable: %s at %d",
+		address, cached.Unclaimed.Available, cached.Unclaimed.Unavailable, tip,
+	)
 
-	return blocks, nil
+	return cached, nil
 }
 
-func (server *Server) doGetClaim(address string) (*rpc.Unclaimed, error) {
+// claimDetails calc the claim breakdown of address's unclaimed utxos at the index tip, return the tip. neo_utxo
+// is often ahead of the index, the utxos are viewed at the tip
+func (server *Server) claimDetails(address string) (int64, []*claim.Detail, error) {
 
-	logger.DebugF("[doGetClaim]start get claim :%s", address)
-
-	utxos, err := server.unclaimed(address)
+	tip, err := server.sysfee.Height()
 
 	if err != nil {
-		return nil, fmt.Errorf("[doGetClaim]get %s get unclaimed utxo err:\n\t%s", address, err)
+		return -1, nil, err
 	}
 
-	logger.DebugF("[doGetClaim]get address %s unclaimed utxo -- success", address)
-
-	start, end := claim.CalcBlockRange(utxos)
-
-	logger.Debug("[doGetClaim]start get blocks", start, end)
-	blocks, err := server.getBlocks(start, end)
-	logger.Debug("[doGetClaim]end get blocks", len(blocks), blocks[len(blocks)-1].Block)
+	fingerprint, err := utxoFingerprint(server.engine, address, server.network.NEOAsset, tip)
 
 	if err != nil {
-		return nil, nil
+		return -1, nil, newAppError(
+			JSONRPCDatastoreUnavailable, nil,
+			"[claimDetails]get %s unclaimed utxo fingerprint err:\n\t%s", address, err,
+		)
 	}
 
-	logger.DebugF("[doGetClaim] calc address %s unclaimed gas", address)
+	if fingerprint == "" {
+		return tip, nil, nil
+	}
 
-	unavailable, available, err := claim.CalcUnclaimedGas(utxos, blocks)
+	// extend the checkpoint of an unchanged utxo set instead of summing the fees again from its oldest utxo
+	if server.sysfee.Incomplete() == nil {
+		if details := server.extendCheckpoint(address, fingerprint, tip); details != nil {
+			return tip, details, nil
+		}
+	}
+
+	utxos, err := server.unclaimed(address)
 
 	if err != nil {
-		return nil, fmt.Errorf("[doGetClaim]get address %s unclaimed gas fee err:\n\t%s", address, err)
+		return -1, nil, newAppError(
+			JSONRPCDatastoreUnavailable, nil,
+			"[claimDetails]get %s get unclaimed utxo err:\n\t%s", address, err,
+		)
 	}
 
-	logger.DebugF("[doGetClaim] calc address %s unclaimed gas -- success", address)
+	logger.DebugF("[claimDetails]get address %s unclaimed utxo -- success", address)
 
-	claims := make([]*rpc.UTXO, 0)
+	details, err := server.detailsAt(utxos, tip)
 
-	for _, utxo := range utxos {
-		if utxo.SpentBlock != -1 {
-			claims = append(claims, utxo)
+	if err != nil {
+		if _, ok := err.(*AppError); ok {
+			return tip, nil, err
 		}
+
+		return tip, nil, fmt.Errorf("[claimDetails]get address %s unclaimed gas fee err:\n\t%s", address, err)
 	}
 
-	unclaimed := &rpc.Unclaimed{
-		Available:   fmt.Sprintf("%.8f", round(available, 8)),
-		Unavailable: fmt.Sprintf("%.8f", round(unavailable, 8)),
-		Claims:      claims,
+	if len(details) == 0 {
+		return tip, nil, nil
 	}
 
-	// jsondata, _ := json.Marshal(unclaimed)
+	logger.DebugF("[claimDetails] calc address %s unclaimed gas through %d", address, tip)
 
-	logger.DebugF("[doGetClaim]finish get claim: %s available: %.8f unavailable: %.8f", address, round(available, 8), round(unavailable, 8))
+	server.saveCheckpoint(address, claim.NewCheckpoint(tip, fingerprint, details))
 
-	return unclaimed, nil
+	return tip, details, nil
 }
 
-func round(f float64, n int) float64 {
-	data := fmt.Sprintf("%.9f", f)
+// detailsAt the claim breakdown of utxos viewed at tip, the utxos created after tip are left out and
+// the ones spent after tip accrue through tip unspent
+func (server *Server) detailsAt(utxos []*rpc.UTXO, tip int64) ([]*claim.Detail, error) {
 
-	data = data[0 : len(data)-1]
+	utxos = claim.RewindUTXOs(utxos, tip)
+
+	if len(utxos) == 0 {
+		return nil, nil
+	}
+
+	// unspent utxos accrue to the index tip, which is not the chain tip while the index stalls at missing blocks
+	if incomplete := server.sysfee.Incomplete(); incomplete != nil {
+		for _, utxo := range utxos {
+			if utxo.SpentBlock == -1 {
+				return nil, newAppError(
+					JSONRPCIncompleteBlocks, map[string]interface{}{"indexed": tip, "blocks": incomplete},
+					"[claimDetails]sys_fee index stop at %d, %s", tip, incomplete,
+				)
+			}
+		}
+	}
+
+	// the index moves on meanwhile, pin it to tip
+	source, err := claim.AtHeight(server.sysfee, tip)
+
+	if err != nil {
+		return nil, err
+	}
 
-	r, _ := strconv.ParseFloat(data, 8)
+	_, details, err := server.network.Details(utxos, source)
 
-	return r
+	return details, err
 }
@@ -0,0 +1,235 @@
+package insight
+
+import (
+	"expvar"
+	"time"
+)
+
+// claim sync metrics, published by expvar
+var (
+	syncQueueDepth  = expvar.NewInt("claim_sync_queue_depth")
+	syncWorkerCount = expvar.NewInt("claim_sync_workers")
+	syncBusyWorkers = expvar.NewInt("claim_sync_busy_workers")
+	syncDropped     = expvar.NewInt("claim_sync_dropped")
+	syncTimeouts    = expvar.NewInt("claim_sync_timeouts")
+	syncAbandoned   = expvar.NewInt("claim_sync_abandoned")
+	syncUtilisation = expvar.NewFloat("claim_sync_utilisation")
+	syncScheduled   = expvar.NewInt("claim_sync_scheduled")
+	syncLeased      = expvar.NewInt("claim_sync_leased")
+	syncRecovered   = expvar.NewInt("claim_sync_recovered")
+)
+
+func updateSyncUtilisation() {
+	if workers := syncWorkerCount.Value(); workers > 0 {
+		syncUtilisation.Set(float64(syncBusyWorkers.Value()) / float64(workers))
+	}
+}
+
+// scheduleSync schedule the refresh of address due now with insight.sync_queue.attempts, an address
+// scheduled already (by any replica) is not scheduled again
+func (server *Server) scheduleSync(address string) {
+	attempts := server.syncAttempts
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	scheduled, err := server.syncStore.schedule(address, attempts, time.Now())
+
+	if err != nil {
+		logger.ErrorF("schedule sync address %s err, %s", address, err)
+		return
+	}
+
+	if scheduled {
+		logger.DebugF("queued claim task: %s", address)
+	}
+}
+
+// enqueueSync hand leased address to the workers without blocking, return false if the queue is
+// full. The lease of a dropped address expires and it is leased again
+func (server *Server) enqueueSync(address *syncAddress) bool {
+	select {
+	case server.syncChan <- address:
+		syncQueueDepth.Add(1)
+		return true
+	default:
+		syncDropped.Add(1)
+
+		logger.WarnF("sync queue full (%d), drop address %s", cap(server.syncChan), address)
+
+		return false
+	}
+}
+
+// extendSync extend the lease of address by insight.sync_queue.lease
+func (server *Server) extendSync(address *syncAddress) {
+	extended, err := server.syncStore.extend(address, time.Now().Add(server.syncLease))
+
+	if err != nil {
+		logger.ErrorF("extend sync address %s lease err, %s", address, err)
+		return
+	}
+
+	if !extended {
+		logger.WarnF("sync address %s lease lost", address)
+	}
+}
+
+// completeSync release the lease of address, reschedule it after delay with times refreshes left
+func (server *Server) completeSync(address *syncAddress, times int, delay time.Duration) {
+	completed, err := server.syncStore.complete(address, times, time.Now().Add(delay))
+
+	if err != nil {
+		logger.ErrorF("complete sync address %s err, %s", address, err)
+		return
+	}
+
+	if !completed {
+		logger.WarnF("sync address %s lease lost", address)
+	}
+}
+
+// pollSync lease the due jobs for the idle workers every insight.sync_queue.poll, never return
+func (server *Server) pollSync() {
+	ticker := time.NewTicker(server.syncPoll)
+
+	for range ticker.C {
+		server.pollSyncOnce(time.Now())
+	}
+}
+
+func (server *Server) pollSyncOnce(now time.Time) {
+	defer server.updateSyncDepth()
+
+	// the abandoned claims still compute, they take the capacity of a worker until they return
+	idle := server.syncWorkers - int(syncBusyWorkers.Value()+syncAbandoned.Value()) - len(server.syncChan)
+
+	if idle <= 0 {
+		return
+	}
+
+	jobs, err := server.syncStore.lease(now, server.syncLease, idle)
+
+	if err != nil {
+		logger.ErrorF("lease sync jobs err, %s", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if job.recovered {
+			syncRecovered.Add(1)
+			logger.WarnF("recover sync address %s of expired lease", job)
+		}
+
+		server.enqueueSync(job)
+	}
+}
+
+func (server *Server) updateSyncDepth() {
+	scheduled, leased, err := server.syncStore.depth()
+
+	if err != nil {
+		logger.ErrorF("get sync queue depth err, %s", err)
+		return
+	}
+
+	syncScheduled.Set(scheduled)
+	syncLeased.Set(leased)
+}
+
+// runSyncWorkers start insight.sync_concurrency workers refreshing the cached claims
+func (server *Server) runSyncWorkers() {
+	if server.syncWorkers < 1 {
+		server.syncWorkers = 1
+	}
+
+	syncWorkerCount.Add(int64(server.syncWorkers))
+	updateSyncUtilisation()
+
+	for i := 0; i < server.syncWorkers; i++ {
+		go server.syncWorker()
+	}
+
+	go server.pollSync()
+}
+
+func (server *Server) syncWorker() {
+	for address := range server.syncChan {
+		syncQueueDepth.Add(-1)
+
+		syncBusyWorkers.Add(1)
+		updateSyncUtilisation()
+
+		server.syncCached(address)
+
+		syncBusyWorkers.Add(-1)
+		updateSyncUtilisation()
+	}
+}
+
+// syncCached refresh the cached claim of address
+func (server *Server) syncCached(address *syncAddress) {
+	server.syncClaim(address, server.doGetClaim)
+}
+
+// syncClaim refresh the cached claim of address with compute, its lease is extended while it runs. A claim
+// slower than insight.sync_timeout is abandoned and retried after insight.sync_queue.retry_delay while
+// attempts are left, the abandoned computation is still cached when it returns
+func (server *Server) syncClaim(address *syncAddress, compute func(address string) (*CachedClaim, error)) {
+
+	logger.DebugF("sync address claimed utxos %s", address)
+
+	startTime := time.Now()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		cached, err := compute(address.Address)
+
+		if err != nil {
+			logger.ErrorF("sync claim for address %s err, %s", address, err)
+			return
+		}
+
+		logger.DebugF("[doGetClaim] claim %s spent times %s", address.Address, time.Now().Sub(startTime))
+
+		if err := server.claimCache.Set(address.Address, cached); err != nil {
+			logger.ErrorF("cached claim for address %s err, %s", address, err)
+			return
+		}
+
+		logger.DebugF(" sync address claimed utxos %s -- success", address)
+	}()
+
+	timeout := time.NewTimer(server.syncTimeout)
+	defer timeout.Stop()
+
+	extend := time.NewTicker(server.syncLease / 2)
+	defer extend.Stop()
+
+	for {
+		select {
+		case <-done:
+			server.completeSync(address, 0, 0)
+			return
+		case <-extend.C:
+			server.extendSync(address)
+		case <-timeout.C:
+			syncTimeouts.Add(1)
+			syncAbandoned.Add(1)
+
+			logger.WarnF("sync claim for address %s timeout after %s, %d attempts left", address, server.syncTimeout, address.Times-1)
+
+			go func() {
+				<-done
+				syncAbandoned.Add(-1)
+			}()
+
+			server.completeSync(address, address.Times-1, server.syncRetry)
+			return
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package insight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnqueueSync(t *testing.T) {
+	server := newTestServer(t)
+
+	server.syncChan = make(chan *syncAddress, 1)
+
+	require.True(t, server.enqueueSync(&syncAddress{Address: "a", Times: 1}))
+
+	dropped := syncDropped.Value()
+
+	require.False(t, server.enqueueSync(&syncAddress{Address: "b", Times: 1}))
+	require.Equal(t, dropped+1, syncDropped.Value())
+	require.Len(t, server.syncChan, 1)
+}
+
+func TestPollSync(t *testing.T) {
+	server := newTestServer(t)
+
+	server.syncChan = make(chan *syncAddress, 4)
+	server.syncStore = newMemorySyncStore()
+	server.syncWorkers = 2
+	server.syncLease = time.Minute
+
+	server.scheduleSync("a")
+	server.scheduleSync("b")
+	server.scheduleSync("c")
+	server.scheduleSync("a")
+
+	now := time.Now().Add(time.Second)
+
+	// two idle workers lease two jobs
+	server.pollSyncOnce(now)
+
+	require.Len(t, server.syncChan, 2)
+	require.Equal(t, int64(1), syncScheduled.Value())
+
+	// no idle worker
+	server.pollSyncOnce(now)
+
+	require.Len(t, server.syncChan, 2)
+
+	a := <-server.syncChan
+	<-server.syncChan
+
+	require.Equal(t, "a", a.Address)
+	require.Equal(t, 1, a.Times)
+
+	server.completeSync(a, 0, 0)
+
+	// b was dropped by a crashed worker, its lease expires and b is recovered
+	recovered := syncRecovered.Value()
+
+	server.pollSyncOnce(now.Add(2 * time.Minute))
+
+	require.Len(t, server.syncChan, 2)
+	require.Equal(t, recovered+1, syncRecovered.Value())
+	require.Equal(t, "b", (<-server.syncChan).Address)
+	require.Equal(t, "c", (<-server.syncChan).Address)
+}
+
+func TestSyncClaim(t *testing.T) {
+	server := newTestServer(t)
+
+	server.syncStore = newMemorySyncStore()
+	server.claimCache = newLRUClaimCache(10, time.Minute)
+	server.syncTimeout = time.Second
+	server.syncLease = 20 * time.Millisecond
+	server.syncAttempts = 2
+	server.syncRetry = time.Minute
+
+	server.scheduleSync("a")
+
+	now := time.Now()
+
+	jobs, err := server.syncStore.lease(now, server.syncLease, 1)
+	require.NoError(t, err)
+
+	var expired []*syncAddress
+
+	// the lease is extended while the claim computes, no replica recovers it
+	server.syncClaim(jobs[0], func(address string) (*CachedClaim, error) {
+		time.Sleep(60 * time.Millisecond)
+		expired, _ = server.syncStore.lease(time.Now(), server.syncLease, 1)
+		return &CachedClaim{Unclaimed: &rpc.Unclaimed{Available: "1"}}, nil
+	})
+
+	require.Empty(t, expired)
+
+	cached, err := server.claimCache.Get("a")
+	require.NoError(t, err)
+	require.Equal(t, "1", cached.Unclaimed.Available)
+
+	pending, err := server.syncStore.pending("a")
+	require.NoError(t, err)
+	require.False(t, pending)
+
+	// a claim slower than the timeout is abandoned and retried with one attempt left
+	server.syncTimeout = 20 * time.Millisecond
+
+	server.scheduleSync("a")
+
+	now = time.Now()
+
+	jobs, err = server.syncStore.lease(now, time.Minute, 1)
+	require.NoError(t, err)
+
+	timeouts, abandoned := syncTimeouts.Value(), syncAbandoned.Value()
+
+	release := make(chan struct{})
+
+	server.syncClaim(jobs[0], func(address string) (*CachedClaim, error) {
+		<-release
+		return &CachedClaim{Unclaimed: &rpc.Unclaimed{Available: "2"}}, nil
+	})
+
+	require.Equal(t, timeouts+1, syncTimeouts.Value())
+	require.Equal(t, abandoned+1, syncAbandoned.Value())
+
+	jobs, err = server.syncStore.lease(now.Add(2*time.Minute), time.Minute, 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, 1, jobs[0].Times)
+	require.False(t, jobs[0].recovered)
+
+	// the abandoned claim is cached when it returns
+	close(release)
+
+	require.Eventually(t, func() bool {
+		cached, _ := server.claimCache.Get("a")
+		return cached != nil && cached.Unclaimed.Available == "2" && syncAbandoned.Value() == abandoned
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,432 @@
+package insight
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dynamicgo/config"
+	"github.com/go-redis/redis"
+)
+
+// syncStore schedule of the claim refresh jobs shared by the insight replicas. A job is either due
+// at a time or leased by one replica until the lease expires, an expired lease (the replica crashed
+// while syncing) is leased again
+type syncStore interface {
+	// schedule address with times refreshes left due at due, false if it is scheduled already. A leased
+	// address is rescheduled when the lease completes, false if that is recorded already
+	schedule(address string, times int, due time.Time) (bool, error)
+	// lease up to limit jobs due at now, the expired leases first, until now+lease
+	lease(now time.Time, lease time.Duration, limit int) ([]*syncAddress, error)
+	// extend the lease of job until until, false if the lease is lost
+	extend(job *syncAddress, until time.Time) (bool, error)
+	// complete leased job, reschedule it at due if times > 0 or it was scheduled while leased, false
+	// if the lease is lost
+	complete(job *syncAddress, times int, due time.Time) (bool, error)
+	// scheduled up to limit scheduled addresses, earliest due first
+	scheduled(limit int) ([]string, error)
+	// pending true if address is scheduled or leased
+	pending(address string) (bool, error)
+	// depth the count of scheduled and leased jobs
+	depth() (scheduled int64, leased int64, err error)
+}
+
+// newSyncStore create the store of insight.sync_queue.backend, redis if the claim cache is in redis
+// else memory by default
+func newSyncStore(cnf *config.Config, client func() *redis.Client) (syncStore, error) {
+	backend := "memory"
+
+	if redisDefault(cnf) {
+		backend = "redis"
+	}
+
+	switch backend := cnf.GetString("insight.sync_queue.backend", backend); backend {
+	case "redis":
+		return newRedisSyncStore(client(), cnf.GetString("insight.sync_queue.prefix", "insight:sync")), nil
+	case "memory":
+		return newMemorySyncStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown insight.sync_queue.backend %s", backend)
+	}
+}
+
+func newLeaseToken() string {
+	data := make([]byte, 8)
+
+	rand.Read(data)
+
+	return hex.EncodeToString(data)
+}
+
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// redisSyncStore syncStore of a due time sorted set, a lease expiry sorted set and the hashes of
+// the refresh times, lease owners and the schedules of leased addresses, every operation is one lua
+// script
+type redisSyncStore struct {
+	client *redis.Client
+	keys   []string
+}
+
+func newRedisSyncStore(client *redis.Client, prefix string) *redisSyncStore {
+	return &redisSyncStore{
+		client: client,
+		keys: []string{
+			prefix + ":due", prefix + ":times", prefix + ":leases", prefix + ":owners", prefix + ":dirty",
+		},
+	}
+}
+
+var syncScheduleScript = redis.NewScript(`
+if redis.call('zscore', KEYS[1], ARGV[1]) then
+	return 0
+end
+if redis.call('zscore', KEYS[3], ARGV[1]) then
+	return redis.call('hsetnx', KEYS[5], ARGV[1], ARGV[2] .. ':' .. ARGV[3])
+end
+redis.call('zadd', KEYS[1], ARGV[3], ARGV[1])
+redis.call('hset', KEYS[2], ARGV[1], ARGV[2])
+return 1
+`)
+
+var syncLeaseScript = redis.NewScript(`
+local jobs = {}
+local expired = redis.call('zrangebyscore', KEYS[3], '-inf', ARGV[1], 'limit', 0, ARGV[3])
+for _, address in ipairs(expired) do
+	table.insert(jobs, address)
+	table.insert(jobs, redis.call('hget', KEYS[2], address) or '0')
+	table.insert(jobs, '1')
+end
+local left = tonumber(ARGV[3]) - #expired
+if left > 0 then
+	local due = redis.call('zrangebyscore', KEYS[1], '-inf', ARGV[1], 'limit', 0, left)
+	for _, address in ipairs(due) do
+		redis.call('zrem', KEYS[1], address)
+		table.insert(jobs, address)
+		table.insert(jobs, redis.call('hget', KEYS[2], address) or '0')
+		table.insert(jobs, '0')
+	end
+end
+for i = 1, #jobs, 3 do
+	redis.call('zadd', KEYS[3], ARGV[2], jobs[i])
+	redis.call('hset', KEYS[4], jobs[i], ARGV[4])
+end
+return jobs
+`)
+
+var syncExtendScript = redis.NewScript(`
+if redis.call('hget', KEYS[4], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('zadd', KEYS[3], ARGV[3], ARGV[1])
+return 1
+`)
+
+var syncCompleteScript = redis.NewScript(`
+if redis.call('hget', KEYS[4], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('zrem', KEYS[3], ARGV[1])
+redis.call('hdel', KEYS[4], ARGV[1])
+local times, due = tonumber(ARGV[3]), tonumber(ARGV[4])
+local dirty = redis.call('hget', KEYS[5], ARGV[1])
+if dirty then
+	redis.call('hdel', KEYS[5], ARGV[1])
+	local dirtyTimes, dirtyDue = string.match(dirty, '^(%d+):(%d+)$')
+	if times <= 0 or tonumber(dirtyDue) < due then
+		due = tonumber(dirtyDue)
+	end
+	times = math.max(times, tonumber(dirtyTimes))
+end
+if times > 0 then
+	redis.call('zadd', KEYS[1], due, ARGV[1])
+	redis.call('hset', KEYS[2], ARGV[1], times)
+else
+	redis.call('hdel', KEYS[2], ARGV[1])
+end
+return 1
+`)
+
+func (store *redisSyncStore) schedule(address string, times int, due time.Time) (bool, error) {
+	added, err := syncScheduleScript.Run(store.client, store.keys, address, times, unixMillis(due)).Result()
+
+	return added == int64(1), err
+}
+
+func (store *redisSyncStore) lease(now time.Time, lease time.Duration, limit int) ([]*syncAddress, error) {
+
+	token := newLeaseToken()
+
+	result, err := syncLeaseScript.Run(
+		store.client, store.keys, unixMillis(now), unixMillis(now.Add(lease)), limit, token,
+	).Result()
+
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := result.([]interface{})
+
+	if !ok || len(values)%3 != 0 {
+		return nil, fmt.Errorf("unexpected sync lease result %v", result)
+	}
+
+	jobs := make([]*syncAddress, 0, len(values)/3)
+
+	for i := 0; i < len(values); i += 3 {
+		address, _ := values[i].(string)
+		timesValue, _ := values[i+1].(string)
+		recovered, _ := values[i+2].(string)
+
+		times, err := strconv.Atoi(timesValue)
+
+		if err != nil {
+			return nil, fmt.Errorf("sync job %s times %s err, %s", address, timesValue, err)
+		}
+
+		jobs = append(jobs, &syncAddress{
+			Address:   address,
+			Times:     times,
+			token:     token,
+			recovered: recovered == "1",
+		})
+	}
+
+	return jobs, nil
+}
+
+func (store *redisSyncStore) extend(job *syncAddress, until time.Time) (bool, error) {
+	extended, err := syncExtendScript.Run(store.client, store.keys, job.Address, job.token, unixMillis(until)).Result()
+
+	return extended == int64(1), err
+}
+
+func (store *redisSyncStore) complete(job *syncAddress, times int, due time.Time) (bool, error) {
+	completed, err := syncCompleteScript.Run(
+		store.client, store.keys, job.Address, job.token, times, unixMillis(due),
+	).Result()
+
+	return completed == int64(1), err
+}
+
+func (store *redisSyncStore) scheduled(limit int) ([]string, error) {
+	return store.client.ZRange(store.keys[0], 0, int64(limit-1)).Result()
+}
+
+func (store *redisSyncStore) pending(address string) (bool, error) {
+	return store.client.HExists(store.keys[1], address).Result()
+}
+
+func (store *redisSyncStore) depth() (int64, int64, error) {
+	scheduled, err := store.client.ZCard(store.keys[0]).Result()
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	leased, err := store.client.ZCard(store.keys[2]).Result()
+
+	return scheduled, leased, err
+}
+
+type memoryLease struct {
+	until time.Time
+	token string
+}
+
+// memoryDirty schedule of a leased address, applied when the lease completes
+type memoryDirty struct {
+	times int
+	due   time.Time
+}
+
+// memorySyncStore in process syncStore of a single insight instance, the schedule is lost on restart
+type memorySyncStore struct {
+	mutex  sync.Mutex
+	due    map[string]time.Time
+	times  map[string]int
+	leases map[string]*memoryLease
+	dirty  map[string]*memoryDirty
+}
+
+func newMemorySyncStore() *memorySyncStore {
+	return &memorySyncStore{
+		due:    make(map[string]time.Time),
+		times:  make(map[string]int),
+		leases: make(map[string]*memoryLease),
+		dirty:  make(map[string]*memoryDirty),
+	}
+}
+
+func (store *memorySyncStore) schedule(address string, times int, due time.Time) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.due[address]; ok {
+		return false, nil
+	}
+
+	if _, ok := store.leases[address]; ok {
+		if _, ok := store.dirty[address]; ok {
+			return false, nil
+		}
+
+		store.dirty[address] = &memoryDirty{times: times, due: due}
+
+		return true, nil
+	}
+
+	store.due[address] = due
+	store.times[address] = times
+
+	return true, nil
+}
+
+// sortedBefore addresses of times before now, earliest first
+func sortedBefore(times map[string]time.Time, now time.Time) []string {
+	addresses := make([]string, 0)
+
+	for address, t := range times {
+		if !t.After(now) {
+			addresses = append(addresses, address)
+		}
+	}
+
+	sort.Slice(addresses, func(i, j int) bool {
+		if times[addresses[i]].Equal(times[addresses[j]]) {
+			return addresses[i] < addresses[j]
+		}
+
+		return times[addresses[i]].Before(times[addresses[j]])
+	})
+
+	return addresses
+}
+
+func (store *memorySyncStore) lease(now time.Time, lease time.Duration, limit int) ([]*syncAddress, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	token := newLeaseToken()
+
+	jobs := make([]*syncAddress, 0, limit)
+
+	expired := make(map[string]time.Time)
+
+	for address, held := range store.leases {
+		expired[address] = held.until
+	}
+
+	for _, address := range sortedBefore(expired, now) {
+		if len(jobs) == limit {
+			break
+		}
+
+		jobs = append(jobs, &syncAddress{Address: address, Times: store.times[address], recovered: true})
+	}
+
+	for _, address := range sortedBefore(store.due, now) {
+		if len(jobs) == limit {
+			break
+		}
+
+		delete(store.due, address)
+
+		jobs = append(jobs, &syncAddress{Address: address, Times: store.times[address]})
+	}
+
+	for _, job := range jobs {
+		job.token = token
+		store.leases[job.Address] = &memoryLease{until: now.Add(lease), token: token}
+	}
+
+	return jobs, nil
+}
+
+func (store *memorySyncStore) extend(job *syncAddress, until time.Time) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	lease, ok := store.leases[job.Address]
+
+	if !ok || lease.token != job.token {
+		return false, nil
+	}
+
+	lease.until = until
+
+	return true, nil
+}
+
+func (store *memorySyncStore) complete(job *syncAddress, times int, due time.Time) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if lease, ok := store.leases[job.Address]; !ok || lease.token != job.token {
+		return false, nil
+	}
+
+	delete(store.leases, job.Address)
+
+	if dirty, ok := store.dirty[job.Address]; ok {
+		delete(store.dirty, job.Address)
+
+		if times <= 0 || dirty.due.Before(due) {
+			due = dirty.due
+		}
+
+		if dirty.times > times {
+			times = dirty.times
+		}
+	}
+
+	if times > 0 {
+		store.due[job.Address] = due
+		store.times[job.Address] = times
+	} else {
+		delete(store.times, job.Address)
+	}
+
+	return true, nil
+}
+
+func (store *memorySyncStore) scheduled(limit int) ([]string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	addresses := make([]string, 0)
+
+	for address := range store.due {
+		addresses = append(addresses, address)
+	}
+
+	sort.Slice(addresses, func(i, j int) bool { return store.due[addresses[i]].Before(store.due[addresses[j]]) })
+
+	if len(addresses) > limit {
+		addresses = addresses[:limit]
+	}
+
+	return addresses, nil
+}
+
+func (store *memorySyncStore) pending(address string) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	_, ok := store.times[address]
+
+	return ok, nil
+}
+
+func (store *memorySyncStore) depth() (int64, int64, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return int64(len(store.due)), int64(len(store.leases)), nil
+}
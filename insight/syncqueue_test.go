@@ -0,0 +1,157 @@
+package insight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dynamicgo/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySyncStore(t *testing.T) {
+	store := newMemorySyncStore()
+
+	now := time.Now()
+
+	ok, err := store.schedule("a", 2, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = store.schedule("b", 2, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = store.schedule("a", 2, now)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	jobs, err := store.lease(now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "a", jobs[0].Address)
+	require.False(t, jobs[0].recovered)
+
+	// a leased job is scheduled again once, when the lease completes
+	ok, err = store.schedule("a", 2, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = store.schedule("a", 2, now)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	scheduled, leased, err := store.depth()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), scheduled)
+	require.Equal(t, int64(1), leased)
+
+	// an extended lease does not expire
+	ok, err = store.extend(jobs[0], now.Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	recovered, err := store.lease(now.Add(30*time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Empty(t, recovered)
+
+	// the lease of a expires, another replica leases it with b
+	recovered, err = store.lease(now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, recovered, 2)
+	require.Equal(t, "a", recovered[0].Address)
+	require.True(t, recovered[0].recovered)
+	require.Equal(t, "b", recovered[1].Address)
+
+	// the crashed lease is lost
+	ok, err = store.complete(jobs[0], 1, now)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = store.extend(jobs[0], now.Add(time.Hour))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// the schedule while leased is due earlier with more times left
+	ok, err = store.complete(recovered[0], 1, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = store.complete(recovered[1], 0, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	addresses, err := store.scheduled(10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, addresses)
+
+	jobs, err = store.lease(now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, 2, jobs[0].Times)
+}
+
+func TestMemorySyncStoreScheduleLeased(t *testing.T) {
+	store := newMemorySyncStore()
+
+	now := time.Now()
+
+	ok, err := store.schedule("a", 1, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	jobs, err := store.lease(now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	// a block touches a while it is syncing
+	ok, err = store.schedule("a", 1, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = store.complete(jobs[0], 0, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	pending, err := store.pending("a")
+	require.NoError(t, err)
+	require.True(t, pending)
+
+	jobs, err = store.lease(now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "a", jobs[0].Address)
+	require.Equal(t, 1, jobs[0].Times)
+
+	// not touched again, the next complete is final
+	ok, err = store.complete(jobs[0], 0, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	pending, err = store.pending("a")
+	require.NoError(t, err)
+	require.False(t, pending)
+}
+
+func TestNewSyncStore(t *testing.T) {
+	cnf, err := config.New([]byte(`{"insight": {"sync_queue": {"backend": "memory"}}}`))
+	require.NoError(t, err)
+
+	store, err := newSyncStore(cnf, nil)
+	require.NoError(t, err)
+	require.IsType(t, &memorySyncStore{}, store)
+
+	cnf, err = config.New([]byte(`{"insight": {"sync_queue": {"backend": "etcd"}}}`))
+	require.NoError(t, err)
+
+	_, err = newSyncStore(cnf, nil)
+	require.Error(t, err)
+
+	// without redis for the claim cache the schedule is in memory
+	cnf, err = config.New([]byte(`{"insight": {"claim_cache": {"backend": "lru"}}}`))
+	require.NoError(t, err)
+	require.False(t, redisDefault(cnf))
+
+	store, err = newSyncStore(cnf, nil)
+	require.NoError(t, err)
+	require.IsType(t, &memorySyncStore{}, store)
+}
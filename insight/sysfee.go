@@ -0,0 +1,364 @@
+package insight
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dynamicgo/config"
+	"github.com/go-xorm/xorm"
+	"github.com/inwecrypto/neo-insight/claim"
+)
+
+// SysFee cumulative sys_fee index of neo_block maintained by insight, Amount is the sys_fee of
+// blocks [0, Block] in whole GAS, the same as the node's GetSysFeeAmount
+type SysFee struct {
+	Block  int64 `xorm:"pk"`
+	Amount int64 `xorm:"notnull"`
+}
+
+// TableName xorm table name
+func (table *SysFee) TableName() string {
+	return "neo_sysfee"
+}
+
+// sysFeeStore the neo_block rows the sys_fee index reads and the neo_sysfee rows it writes
+type sysFeeStore interface {
+	// create the index table if missing
+	create() error
+	// blocks block and sys_fee text of up to limit neo_block rows above block, ordered by block
+	blocks(block int64, limit int64) ([]map[string]string, error)
+	// last the highest indexed block, nil if nothing indexed
+	last() (*SysFee, error)
+	// get the indexed block, nil if not indexed
+	get(block int64) (*SysFee, error)
+	// insert fees, the blocks another writer indexed already are kept
+	insert(fees []*SysFee) error
+}
+
+// sysFeeIndex the cumulative sys_fee table with an in-process lookup cache
+type sysFeeIndex struct {
+	mutex      sync.RWMutex
+	store      sysFeeStore
+	cache      map[int64]int64
+	cacheSize  int
+	height     int64
+	amount     int64
+	batch      int64
+	interval   time.Duration
+	loaded     bool
+	incomplete *claim.Continuity
+}
+
+func newSysFeeIndex(cnf *config.Config, engine *xorm.Engine) *sysFeeIndex {
+	return &sysFeeIndex{
+		store:     &xormSysFeeStore{engine: engine},
+		cache:     make(map[int64]int64),
+		cacheSize: int(cnf.GetInt64("insight.sysfee.cache_size", 1000000)),
+		height:    -1,
+		batch:     cnf.GetInt64("insight.sysfee.batch", 10000),
+		interval:  time.Second * cnf.GetDuration("insight.sysfee.interval", 5),
+	}
+}
+
+// Indexed the highest indexed block, -1 if nothing indexed
+func (index *sysFeeIndex) Indexed() int64 {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	return index.height
+}
+
+// Height the highest indexed block, implements claim.BlockFeeSource
+func (index *sysFeeIndex) Height() (int64, error) {
+	height := index.Indexed()
+
+	if height == -1 {
+		return -1, newAppError(JSONRPCIndexerBehind, nil, "sys_fee index is empty")
+	}
+
+	return height, nil
+}
+
+// Incomplete the missing or duplicate blocks the index stopped at, nil if the index is not stalled
+func (index *sysFeeIndex) Incomplete() *claim.Continuity {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	return index.incomplete
+}
+
+// Run index new blocks periodically, never return
+func (index *sysFeeIndex) Run() {
+	for {
+		if _, err := index.Sync(); err != nil {
+			logger.ErrorF("sync sys_fee index err, %s", err)
+		}
+
+		time.Sleep(index.interval)
+	}
+}
+
+// Sync index the blocks added since the last indexed block, it is also the backfill of an empty index.
+// Return the indexed height
+func (index *sysFeeIndex) Sync() (int64, error) {
+
+	if err := index.load(); err != nil {
+		return -1, err
+	}
+
+	for {
+		height, amount := index.Indexed(), index.amount
+
+		rows, err := index.store.blocks(height, index.batch)
+
+		if err != nil {
+			return height, err
+		}
+
+		if len(rows) == 0 {
+			index.stalled(nil)
+			return height, nil
+		}
+
+		blocks := make([]int64, 0, len(rows))
+
+		for _, row := range rows {
+			block, err := strconv.ParseInt(row["block"], 10, 64)
+
+			if err != nil {
+				return height, err
+			}
+
+			blocks = append(blocks, block)
+		}
+
+		// the index never skips a block, it stops before the first missing or duplicate height
+		continuity := claim.CheckContinuity(blocks, height+1, blocks[len(blocks)-1])
+
+		stop := continuity.First()
+
+		fees := make([]*SysFee, 0, len(rows))
+
+		for i, row := range rows {
+			if stop != -1 && blocks[i] >= stop {
+				break
+			}
+
+			sysfee, err := claim.ParseFixed8(row["sys_fee"])
+
+			if err != nil {
+				return height, err
+			}
+
+			amount += claim.WholeGas(sysfee)
+			height = blocks[i]
+
+			fees = append(fees, &SysFee{Block: height, Amount: amount})
+		}
+
+		if stop != -1 {
+			logger.WarnF("sys_fee index stop at %d, neo_block %s", height, continuity)
+		}
+
+		if len(fees) > 0 {
+			if err := index.store.insert(fees); err != nil {
+				// read the last indexed block again on the next sync, another writer may have moved it
+				index.loaded = false
+				return index.Indexed(), err
+			}
+
+			index.mutex.Lock()
+			index.height = height
+			index.amount = amount
+			index.mutex.Unlock()
+
+			logger.DebugF("sys_fee index height %d", height)
+		}
+
+		if stop != -1 {
+			index.stalled(continuity)
+			return height, nil
+		}
+
+		index.stalled(nil)
+
+		if len(rows) < int(index.batch) {
+			return height, nil
+		}
+	}
+}
+
+func (index *sysFeeIndex) stalled(continuity *claim.Continuity) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.incomplete = continuity
+}
+
+// load create the index table and read the last indexed block once
+func (index *sysFeeIndex) load() error {
+	if index.loaded {
+		return nil
+	}
+
+	if err := index.store.create(); err != nil {
+		return err
+	}
+
+	last, err := index.store.last()
+
+	if err != nil {
+		return err
+	}
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if last != nil {
+		index.height = last.Block
+		index.amount = last.Amount
+	}
+
+	index.loaded = true
+
+	return nil
+}
+
+// xormSysFeeStore the sys_fee index in the indexer database
+type xormSysFeeStore struct {
+	engine *xorm.Engine
+}
+
+// sysFeeInsertRows rows per insert statement, within the postgres bind parameter limit
+const sysFeeInsertRows = 1000
+
+func (store *xormSysFeeStore) create() error {
+	return store.engine.Sync2(new(SysFee))
+}
+
+func (store *xormSysFeeStore) blocks(block int64, limit int64) ([]map[string]string, error) {
+	return store.engine.QueryString(
+		`select block, sys_fee::text as sys_fee from neo_block where block > ? order by block limit ?`,
+		block, limit,
+	)
+}
+
+func (store *xormSysFeeStore) last() (*SysFee, error) {
+	last := &SysFee{}
+
+	found, err := store.engine.Desc("block").Limit(1).Get(last)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return last, nil
+}
+
+func (store *xormSysFeeStore) get(block int64) (*SysFee, error) {
+	fee := &SysFee{}
+
+	found, err := store.engine.Where(`block = ?`, block).Get(fee)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return fee, nil
+}
+
+// insert fees in one transaction, the backfill cli and the replicas index the same blocks
+func (store *xormSysFeeStore) insert(fees []*SysFee) error {
+	session := store.engine.NewSession()
+	defer session.Close()
+
+	if err := session.Begin(); err != nil {
+		return err
+	}
+
+	for len(fees) > 0 {
+		rows := fees
+
+		if len(rows) > sysFeeInsertRows {
+			rows = rows[:sysFeeInsertRows]
+		}
+
+		fees = fees[len(rows):]
+
+		values := make([]string, 0, len(rows))
+		args := make([]interface{}, 0, 2*len(rows))
+
+		for _, fee := range rows {
+			values = append(values, "(?, ?)")
+			args = append(args, fee.Block, fee.Amount)
+		}
+
+		sql := `insert into neo_sysfee (block, amount) values ` + strings.Join(values, ", ") +
+			` on conflict (block) do nothing`
+
+		if _, err := session.Exec(sql, args...); err != nil {
+			session.Rollback()
+			return err
+		}
+	}
+
+	return session.Commit()
+}
+
+// SysFeeAmount cumulative sys_fee of blocks [0, height] in whole GAS, implements claim.BlockFeeSource
+func (index *sysFeeIndex) SysFeeAmount(height int64) (int64, error) {
+
+	if height == -1 {
+		return 0, nil
+	}
+
+	index.mutex.RLock()
+	amount, ok := index.cache[height]
+	indexed := index.height
+	index.mutex.RUnlock()
+
+	if ok {
+		return amount, nil
+	}
+
+	if height > indexed {
+		if incomplete := index.Incomplete(); incomplete != nil {
+			return 0, newAppError(
+				JSONRPCIncompleteBlocks, map[string]interface{}{"height": height, "indexed": indexed, "blocks": incomplete},
+				"sys_fee index stop at %d, %s", indexed, incomplete,
+			)
+		}
+
+		return 0, newAppError(
+			JSONRPCIndexerBehind, map[string]interface{}{"height": height, "indexed": indexed},
+			"sys_fee index height %d is behind %d", indexed, height,
+		)
+	}
+
+	fee, err := index.store.get(height)
+
+	if err != nil {
+		return 0, newAppError(JSONRPCDatastoreUnavailable, nil, "get sys_fee amount of %d err, %s", height, err)
+	}
+
+	if fee == nil {
+		return 0, newAppError(
+			JSONRPCIndexerBehind, map[string]interface{}{"height": height},
+			"sys_fee index has no block %d", height,
+		)
+	}
+
+	index.mutex.Lock()
+
+	if len(index.cache) >= index.cacheSize {
+		index.cache = make(map[int64]int64)
+	}
+
+	index.cache[height] = fee.Amount
+
+	index.mutex.Unlock()
+
+	return fee.Amount, nil
+}
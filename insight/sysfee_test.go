@@ -0,0 +1,122 @@
+package insight
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memorySysFeeStore neo_block and neo_sysfee shared by the indexers of a test, insert keeps the blocks
+// indexed already like the postgres store
+type memorySysFeeStore struct {
+	mutex  sync.Mutex
+	sysfee []string
+	fees   map[int64]*SysFee
+	fail   error
+}
+
+func (store *memorySysFeeStore) create() error {
+	return nil
+}
+
+func (store *memorySysFeeStore) blocks(block int64, limit int64) ([]map[string]string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	rows := make([]map[string]string, 0)
+
+	for i := block + 1; i < int64(len(store.sysfee)) && int64(len(rows)) < limit; i++ {
+		rows = append(rows, map[string]string{"block": strconv.FormatInt(i, 10), "sys_fee": store.sysfee[i]})
+	}
+
+	return rows, nil
+}
+
+func (store *memorySysFeeStore) last() (*SysFee, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var last *SysFee
+
+	for _, fee := range store.fees {
+		if last == nil || fee.Block > last.Block {
+			last = fee
+		}
+	}
+
+	return last, nil
+}
+
+func (store *memorySysFeeStore) get(block int64) (*SysFee, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.fees[block], nil
+}
+
+func (store *memorySysFeeStore) insert(fees []*SysFee) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.fail != nil {
+		return store.fail
+	}
+
+	for _, fee := range fees {
+		if _, ok := store.fees[fee.Block]; !ok {
+			store.fees[fee.Block] = fee
+		}
+	}
+
+	return nil
+}
+
+func newTestSysFeeIndex(store sysFeeStore) *sysFeeIndex {
+	return &sysFeeIndex{store: store, cache: make(map[int64]int64), cacheSize: 10, height: -1, batch: 2}
+}
+
+func TestSysFeeIndexWriters(t *testing.T) {
+	OpenLogger()
+
+	store := &memorySysFeeStore{sysfee: []string{"0", "1", "2.5", "0", "3"}, fees: make(map[int64]*SysFee)}
+
+	server, backfill := newTestSysFeeIndex(store), newTestSysFeeIndex(store)
+
+	// both load the empty index, the server indexes the range first
+	require.NoError(t, backfill.load())
+
+	height, err := server.Sync()
+	require.NoError(t, err)
+	require.Equal(t, int64(4), height)
+
+	// the backfill writes the same range
+	height, err = backfill.Sync()
+	require.NoError(t, err)
+	require.Equal(t, int64(4), height)
+
+	amount, err := backfill.SysFeeAmount(4)
+	require.NoError(t, err)
+	require.Equal(t, int64(6), amount)
+
+	// a failed insert reloads the index, the backfill indexed the new blocks meanwhile
+	store.sysfee = append(store.sysfee, "1", "1")
+	store.fail = errors.New("connection reset")
+
+	height, err = server.Sync()
+	require.Error(t, err)
+	require.Equal(t, int64(4), height)
+
+	store.fail = nil
+
+	height, err = backfill.Sync()
+	require.NoError(t, err)
+	require.Equal(t, int64(6), height)
+
+	height, err = server.Sync()
+	require.NoError(t, err)
+	require.Equal(t, int64(6), height)
+	require.Equal(t, int64(8), server.amount)
+}
@@ -0,0 +1,398 @@
+package insight
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dynamicgo/config"
+	"github.com/ybbus/jsonrpc"
+)
+
+// Upstream errors
+var (
+	ErrNoUpstream = errors.New("no available upstream neo node")
+)
+
+// nonIdempotentMethods node methods which must not be retried on another node
+var nonIdempotentMethods = map[string]bool{
+	"sendrawtransaction": true,
+	"submitblock":        true,
+}
+
+// upstreamNode one neo node the proxy forward requests to
+type upstreamNode struct {
+	mutex     sync.Mutex
+	url       string
+	height    int64
+	healthy   bool
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// upstreamResponse buffered node response
+type upstreamResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Node       string
+}
+
+// upstreamPool neo node pool with periodic health check, lag filter and per node circuit breaker
+type upstreamPool struct {
+	mutex           sync.RWMutex
+	nodes           []*upstreamNode
+	next            int
+	bestHeight      int64
+	maxLag          int64
+	retries         int
+	healthInterval  time.Duration
+	healthTimeout   time.Duration
+	breakerFailures int
+	breakerCooldown time.Duration
+	httpClient      *http.Client
+}
+
+func newUpstreamPool(cnf *config.Config) (*upstreamPool, error) {
+
+	var urls []string
+
+	if cnf.Has("insight.upstream.nodes") {
+		if err := cnf.GetObject("insight.upstream.nodes", &urls); err != nil {
+			return nil, fmt.Errorf("load insight.upstream.nodes err, %s", err)
+		}
+	}
+
+	if len(urls) == 0 {
+		urls = []string{cnf.GetString("insight.neo", "http://xxxxxx:10332")}
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   time.Second * cnf.GetDuration("insight.upstream.dial_timeout", 5),
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          int(cnf.GetInt64("insight.upstream.max_idle_conns", 100)),
+		MaxIdleConnsPerHost:   int(cnf.GetInt64("insight.upstream.max_idle_conns_per_host", 32)),
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: time.Second * cnf.GetDuration("insight.upstream.response_timeout", 30),
+		ExpectContinueTimeout: time.Second,
+	}
+
+	pool := &upstreamPool{
+		maxLag:          cnf.GetInt64("insight.upstream.max_lag", 2),
+		retries:         int(cnf.GetInt64("insight.upstream.retries", 2)),
+		healthInterval:  time.Second * cnf.GetDuration("insight.upstream.health_interval", 10),
+		healthTimeout:   time.Second * cnf.GetDuration("insight.upstream.health_timeout", 5),
+		breakerFailures: int(cnf.GetInt64("insight.upstream.breaker_failures", 5)),
+		breakerCooldown: time.Second * cnf.GetDuration("insight.upstream.breaker_cooldown", 30),
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   time.Second * cnf.GetDuration("insight.upstream.timeout", 60),
+		},
+	}
+
+	for _, url := range urls {
+		pool.nodes = append(pool.nodes, &upstreamNode{
+			url:     url,
+			healthy: true,
+		})
+	}
+
+	return pool, nil
+}
+
+// healthCheck check nodes block height periodically, never return
+func (pool *upstreamPool) healthCheck() {
+	pool.checkNodes()
+
+	ticker := time.NewTicker(pool.healthInterval)
+
+	for range ticker.C {
+		pool.checkNodes()
+	}
+}
+
+func (pool *upstreamPool) checkNodes() {
+
+	var wg sync.WaitGroup
+
+	for _, node := range pool.nodes {
+		wg.Add(1)
+
+		go func(node *upstreamNode) {
+			defer wg.Done()
+
+			height, err := pool.blockCount(node)
+
+			node.mutex.Lock()
+			defer node.mutex.Unlock()
+
+			if err != nil {
+				logger.WarnF("upstream node %s health check err, %s", node.url, err)
+				node.healthy = false
+				return
+			}
+
+			node.healthy = true
+			node.height = height
+		}(node)
+	}
+
+	wg.Wait()
+
+	best := int64(0)
+
+	for _, node := range pool.nodes {
+		node.mutex.Lock()
+
+		if node.healthy && node.height > best {
+			best = node.height
+		}
+
+		node.mutex.Unlock()
+	}
+
+	pool.mutex.Lock()
+	pool.bestHeight = best
+	pool.mutex.Unlock()
+
+	logger.DebugF("upstream best height %d", best)
+}
+
+// bestBlockCount the highest block count of healthy nodes, 0 before the first health check
+func (pool *upstreamPool) bestBlockCount() int64 {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	return pool.bestHeight
+}
+
+// blockCount call getblockcount through the pool's http client, the request is canceled after
+// insight.upstream.health_timeout. neogo's rpc.Client calls through http.DefaultClient it does not
+// expose, it can take neither the pool's transport nor a timeout
+func (pool *upstreamPool) blockCount(node *upstreamNode) (int64, error) {
+
+	request, err := http.NewRequest(
+		http.MethodPost, node.url,
+		strings.NewReader(`{"jsonrpc":"2.0","method":"getblockcount","params":[],"id":1}`),
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	ctx, cancel := context.WithTimeout(context.Background(), pool.healthTimeout)
+	defer cancel()
+
+	response, err := pool.httpClient.Do(request.WithContext(ctx))
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	var result struct {
+		Result int64             `json:"result"`
+		Error  *jsonrpc.RPCError `json:"error"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("getblockcount status %s err, %s", response.Status, err)
+	}
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("getblockcount err, %d %s", result.Error.Code, result.Error.Message)
+	}
+
+	return result.Result, nil
+}
+
+// available check if node is healthy, synced within max lag and the circuit breaker allows the call.
+// After cooldown an open breaker let one probe request through (half open)
+func (node *upstreamNode) available(bestHeight, maxLag int64, now time.Time) bool {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	if !node.healthy || bestHeight-node.height > maxLag {
+		return false
+	}
+
+	if node.openUntil.IsZero() {
+		return true
+	}
+
+	if now.Before(node.openUntil) || node.probing {
+		return false
+	}
+
+	node.probing = true
+
+	return true
+}
+
+func (node *upstreamNode) success() {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	node.failures = 0
+	node.probing = false
+	node.openUntil = time.Time{}
+}
+
+func (node *upstreamNode) failure(threshold int, cooldown time.Duration) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	node.failures++
+
+	if node.probing || node.failures >= threshold {
+		logger.WarnF("upstream node %s circuit breaker open for %s", node.url, cooldown)
+		node.openUntil = time.Now().Add(cooldown)
+	}
+
+	node.probing = false
+}
+
+// pick select next available node in round robin, skip nodes already tried
+func (pool *upstreamPool) pick(tried map[*upstreamNode]bool) *upstreamNode {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	now := time.Now()
+
+	for i := 0; i < len(pool.nodes); i++ {
+		node := pool.nodes[(pool.next+i)%len(pool.nodes)]
+
+		if tried[node] {
+			continue
+		}
+
+		if node.available(pool.bestHeight, pool.maxLag, now) {
+			pool.next = (pool.next + i + 1) % len(pool.nodes)
+			return node
+		}
+	}
+
+	return nil
+}
+
+// forward send jsonrpc body to an available node, idempotent calls are retried on other nodes
+// when the node fails with transport error or 5xx status
+func (pool *upstreamPool) forward(r *http.Request, body []byte, idempotent bool) (*upstreamResponse, error) {
+
+	attempts := 1
+
+	if idempotent {
+		attempts += pool.retries
+	}
+
+	tried := make(map[*upstreamNode]bool)
+
+	lastErr := ErrNoUpstream
+
+	for i := 0; i < attempts; i++ {
+		node := pool.pick(tried)
+
+		if node == nil {
+			break
+		}
+
+		tried[node] = true
+
+		response, err := pool.do(node, r, body)
+
+		if err == nil {
+			node.success()
+			return response, nil
+		}
+
+		// the client is gone, it is not a node failure and nobody waits for a retry
+		if r != nil && r.Context().Err() != nil {
+			return nil, err
+		}
+
+		logger.WarnF("forward to upstream node %s err, %s", node.url, err)
+
+		node.failure(pool.breakerFailures, pool.breakerCooldown)
+
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// forwardedFor X-Forwarded-For of r with the client ip appended
+func forwardedFor(r *http.Request) string {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	if prior := r.Header.Get("X-Forwarded-For"); prior != "" {
+		return prior + ", " + client
+	}
+
+	return client
+}
+
+func (pool *upstreamPool) do(node *upstreamNode, r *http.Request, body []byte) (*upstreamResponse, error) {
+
+	request, err := http.NewRequest(http.MethodPost, node.url, bytes.NewReader(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	if r != nil {
+		// the upstream call is canceled with the client request
+		request = request.WithContext(r.Context())
+
+		if ua := r.Header.Get("User-Agent"); ua != "" {
+			request.Header.Set("User-Agent", ua)
+		}
+
+		request.Header.Set("X-Forwarded-For", forwardedFor(r))
+	}
+
+	response, err := pool.httpClient.Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode >= 500 {
+		return nil, fmt.Errorf("upstream status %s", response.Status)
+	}
+
+	return &upstreamResponse{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       data,
+		Node:       node.url,
+	}, nil
+}
@@ -0,0 +1,197 @@
+package insight
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dynamicgo/config"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNode(height int64, fail bool, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		request, err := makeRPCRequest(body)
+
+		if err == nil && request.Method == "getblockcount" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%d}`, request.ID, height)
+			return
+		}
+
+		atomic.AddInt32(calls, 1)
+
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":"ok"}`)
+	}))
+}
+
+func newTestPool(t *testing.T, urls ...string) *upstreamPool {
+	OpenLogger()
+
+	cnf, err := config.New([]byte(fmt.Sprintf(`{"insight":{"upstream":{"nodes":["%s","%s"],"max_lag":2,"retries":1,"breaker_failures":2}}}`, urls[0], urls[1])))
+
+	require.NoError(t, err)
+
+	pool, err := newUpstreamPool(cnf)
+
+	require.NoError(t, err)
+
+	return pool
+}
+
+func TestUpstreamFailover(t *testing.T) {
+	var badCalls, goodCalls int32
+
+	bad := newTestNode(100, true, &badCalls)
+	defer bad.Close()
+
+	good := newTestNode(100, false, &goodCalls)
+	defer good.Close()
+
+	pool := newTestPool(t, bad.URL, good.URL)
+
+	pool.checkNodes()
+
+	for i := 0; i < 4; i++ {
+		response, err := pool.forward(nil, []byte(`{"jsonrpc":"2.0","method":"getblock","params":[1],"id":1}`), true)
+
+		require.NoError(t, err)
+		require.Equal(t, good.URL, response.Node)
+	}
+
+	// the breaker of bad node opens after two failures
+	require.Equal(t, int32(2), badCalls)
+	require.Equal(t, int32(4), goodCalls)
+
+	_, err := pool.forward(nil, []byte(`{"jsonrpc":"2.0","method":"sendrawtransaction","params":["00"],"id":1}`), false)
+
+	require.NoError(t, err)
+}
+
+func TestUpstreamLagging(t *testing.T) {
+	var laggingCalls, syncedCalls int32
+
+	lagging := newTestNode(90, false, &laggingCalls)
+	defer lagging.Close()
+
+	synced := newTestNode(100, false, &syncedCalls)
+	defer synced.Close()
+
+	pool := newTestPool(t, lagging.URL, synced.URL)
+
+	pool.checkNodes()
+
+	for i := 0; i < 3; i++ {
+		_, err := pool.forward(nil, []byte(`{"jsonrpc":"2.0","method":"getbestblockhash","id":1}`), true)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, int32(0), laggingCalls)
+	require.Equal(t, int32(3), syncedCalls)
+}
+
+func TestUpstreamHealthTimeout(t *testing.T) {
+	canceled := make(chan struct{})
+
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	defer stalled.Close()
+
+	synced := newTestNode(100, false, new(int32))
+	defer synced.Close()
+
+	pool := newTestPool(t, stalled.URL, synced.URL)
+
+	pool.healthTimeout = 50 * time.Millisecond
+
+	pool.checkNodes()
+
+	require.Equal(t, int64(100), pool.bestBlockCount())
+	require.False(t, pool.nodes[0].healthy)
+
+	// the stalled call is canceled, not left running
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("stalled getblockcount not canceled")
+	}
+}
+
+func TestUpstreamClientCanceled(t *testing.T) {
+	var calls int32
+
+	canceled := make(chan struct{}, 2)
+
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		if request, err := makeRPCRequest(body); err == nil && request.Method == "getblockcount" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":100}`, request.ID)
+			return
+		}
+
+		atomic.AddInt32(&calls, 1)
+
+		<-r.Context().Done()
+		canceled <- struct{}{}
+	}))
+	defer stalled.Close()
+
+	pool := newTestPool(t, stalled.URL, stalled.URL)
+
+	pool.checkNodes()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+
+	_, err := pool.forward(r, []byte(`{"jsonrpc":"2.0","method":"getblock","params":[1],"id":1}`), true)
+	require.Error(t, err)
+
+	// the upstream call is canceled with the client and not retried
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream call not canceled with the client")
+	}
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	require.Equal(t, 0, pool.nodes[0].failures+pool.nodes[1].failures)
+}
+
+func TestForwardedFor(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.RemoteAddr = "10.0.0.1:52000"
+
+	require.Equal(t, "10.0.0.1", forwardedFor(r))
+
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	require.Equal(t, "203.0.113.7, 10.0.0.1", forwardedFor(r))
+
+	r.RemoteAddr = "[2001:db8::1]:443"
+	r.Header.Del("X-Forwarded-For")
+
+	require.Equal(t, "2001:db8::1", forwardedFor(r))
+}
+
+func TestIsIdempotent(t *testing.T) {
+	require.True(t, isIdempotent([]byte(`{"jsonrpc":"2.0","method":"getblock","params":[1],"id":1}`)))
+	require.False(t, isIdempotent([]byte(`[{"jsonrpc":"2.0","method":"getblock","id":1},{"jsonrpc":"2.0","method":"sendrawtransaction","id":2}]`)))
+	require.False(t, isIdempotent([]byte(`{bad`)))
+}
@@ -0,0 +1,99 @@
+package insight
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/inwecrypto/neogo/tx"
+)
+
+// addressVersion neo address version byte
+const addressVersion = 0x17
+
+// assetAliases well known asset names accepted in place of asset id on the network
+func assetAliases(network *claim.Params) map[string]string {
+	return map[string]string{
+		"NEO": network.NEOAsset,
+		"GAS": network.GasAsset,
+	}
+}
+
+// normalizeAddress validate neo address, the script hash form is also accepted and converted to address.
+// A 0x prefixed script hash is read in big-endian (as shown by neo explorers and node rpc),
+// the unprefixed hex script hash is read in little-endian (as stored in scripts)
+func normalizeAddress(address string) (string, *JSONRPCError) {
+
+	address = strings.TrimSpace(address)
+
+	details := map[string]interface{}{"address": address}
+
+	if hash, ok := decodeScriptHash(address); ok {
+		return tx.EncodeAddress(hash), nil
+	}
+
+	data, version, err := base58.CheckDecode(address)
+
+	if err != nil {
+		return "", appErrorf(JSONRPCInvalidAddress, details, "invalid address %s: %s", address, err)
+	}
+
+	if version != addressVersion || len(data) != 20 {
+		return "", appErrorf(JSONRPCInvalidAddress, details, "invalid address %s: unexpect version or length", address)
+	}
+
+	if _, err := tx.DecodeAddress(address); err != nil {
+		return "", appErrorf(JSONRPCInvalidAddress, details, "invalid address %s: %s", address, err)
+	}
+
+	return address, nil
+}
+
+func decodeScriptHash(hash string) ([]byte, bool) {
+
+	bigEndian := strings.HasPrefix(hash, "0x")
+
+	hash = strings.TrimPrefix(hash, "0x")
+
+	if len(hash) != 40 {
+		return nil, false
+	}
+
+	data, err := hex.DecodeString(hash)
+
+	if err != nil {
+		return nil, false
+	}
+
+	if bigEndian {
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+
+	return data, true
+}
+
+// normalizeAsset validate asset id format and convert it to the 0x prefixed lower case form
+// used by the indexer, asset aliases NEO and GAS are also accepted
+func normalizeAsset(asset string, network *claim.Params) (string, *JSONRPCError) {
+
+	asset = strings.TrimSpace(asset)
+
+	if id, ok := assetAliases(network)[strings.ToUpper(asset)]; ok {
+		return id, nil
+	}
+
+	id := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(asset, "0x"), "0X"))
+
+	if len(id) != 64 {
+		return "", appErrorf(JSONRPCUnknownAsset, map[string]interface{}{"asset": asset}, "unknown asset %s: expect 32 bytes hex id", asset)
+	}
+
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", appErrorf(JSONRPCUnknownAsset, map[string]interface{}{"asset": asset}, "unknown asset %s: expect 32 bytes hex id", asset)
+	}
+
+	return "0x" + id, nil
+}
@@ -0,0 +1,75 @@
+package insight
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/stretchr/testify/require"
+)
+
+const testAddress = "AanTXadhgdHzGbmy5ZBPXxR4iPHMivzVPZ"
+
+func TestNormalizeAddress(t *testing.T) {
+	address, err := normalizeAddress(" " + testAddress + " ")
+
+	require.Nil(t, err)
+	require.Equal(t, testAddress, address)
+
+	_, err = normalizeAddress("AanTXadhgdHzGbmy5ZBPXxR4iPHMivzVPz")
+
+	require.NotNil(t, err)
+	require.Equal(t, JSONRPCInvalidAddress, err.ID)
+
+	_, err = normalizeAddress(base58.CheckEncode(make([]byte, 20), 0x00))
+
+	require.NotNil(t, err)
+	require.Equal(t, JSONRPCInvalidAddress, err.ID)
+
+	_, err = normalizeAddress(base58.CheckEncode(make([]byte, 19), addressVersion))
+
+	require.NotNil(t, err)
+	require.Equal(t, JSONRPCInvalidAddress, err.ID)
+}
+
+func TestNormalizeScriptHash(t *testing.T) {
+	hash, _, err := base58.CheckDecode(testAddress)
+
+	require.NoError(t, err)
+
+	littleEndian := hex.EncodeToString(hash)
+
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+
+	bigEndian := hex.EncodeToString(hash)
+
+	address, rpcerr := normalizeAddress(littleEndian)
+
+	require.Nil(t, rpcerr)
+	require.Equal(t, testAddress, address)
+
+	address, rpcerr = normalizeAddress("0x" + bigEndian)
+
+	require.Nil(t, rpcerr)
+	require.Equal(t, testAddress, address)
+}
+
+func TestNormalizeAsset(t *testing.T) {
+	asset, err := normalizeAsset("neo", claim.MainNet)
+
+	require.Nil(t, err)
+	require.Equal(t, NEOAssert, asset)
+
+	asset, err = normalizeAsset("602C79718B16E442DE58778E148D0B1084E3B2DFFD5DE6B7B16CEE7969282DE7", claim.MainNet)
+
+	require.Nil(t, err)
+	require.Equal(t, GasAssert, asset)
+
+	_, err = normalizeAsset("0x602c79718b16e442de58778e148d0b1084e3b2df", claim.MainNet)
+
+	require.NotNil(t, err)
+	require.Equal(t, JSONRPCUnknownAsset, err.ID)
+}
@@ -0,0 +1,396 @@
+package insight
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/dynamicgo/config"
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/inwecrypto/neogo/tx"
+	"github.com/ybbus/jsonrpc"
+)
+
+// claim verification metrics, published by expvar
+var (
+	verifyChecked      = expvar.NewInt("claim_verify_checked")
+	verifyMismatches   = expvar.NewInt("claim_verify_mismatches")
+	verifySkipped      = expvar.NewInt("claim_verify_skipped")
+	verifyErrors       = expvar.NewInt("claim_verify_errors")
+	verifyMismatchRate = expvar.NewFloat("claim_verify_mismatch_rate")
+)
+
+// verifySkip the address can not be compared at the node height, not a mismatch
+type verifySkip struct {
+	reason string
+}
+
+func (skip *verifySkip) Error() string {
+	return skip.reason
+}
+
+// nodeClaimable one entry of the node's getclaimable
+type nodeClaimable struct {
+	TxID        string      `json:"txid"`
+	N           int         `json:"n"`
+	StartHeight int64       `json:"start_height"`
+	EndHeight   int64       `json:"end_height"`
+	Unclaimed   json.Number `json:"unclaimed"`
+}
+
+// nodeUnclaimed the node's getunclaimed
+type nodeUnclaimed struct {
+	Available   json.Number `json:"available"`
+	Unavailable json.Number `json:"unavailable"`
+}
+
+// referenceNode neo node with getclaimable and getunclaimed (RpcSystemAssetTracker) the local claims
+// are verified against
+type referenceNode struct {
+	url    string
+	client *jsonrpc.RPCClient
+}
+
+func newReferenceNode(url string, timeout time.Duration) *referenceNode {
+	client := jsonrpc.NewRPCClient(url)
+
+	client.SetHTTPClient(&http.Client{Timeout: timeout})
+
+	return &referenceNode{url: url, client: client}
+}
+
+func (node *referenceNode) call(result interface{}, method string, args ...interface{}) error {
+	response, err := node.client.Call(method, args...)
+
+	if err != nil {
+		return err
+	}
+
+	if response.Error != nil {
+		return fmt.Errorf("%s err, %d %s", method, response.Error.Code, response.Error.Message)
+	}
+
+	return response.GetObject(result)
+}
+
+func (node *referenceNode) height() (int64, error) {
+	var count int64
+
+	if err := node.call(&count, "getblockcount"); err != nil {
+		return -1, err
+	}
+
+	return count - 1, nil
+}
+
+// parseNodeGas the node reports gas as double, in plain or exponent notation
+func parseNodeGas(value json.Number) (tx.Fixed8, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return claim.ParseFixed8(string(value))
+}
+
+// reference get the node's claim of address, fail with *verifySkip if the node height moved meanwhile
+func (node *referenceNode) reference(address string) (*claim.Reference, error) {
+
+	height, err := node.height()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var claimable struct {
+		Claimable []*nodeClaimable `json:"claimable"`
+	}
+
+	if err := node.call(&claimable, "getclaimable", address); err != nil {
+		return nil, err
+	}
+
+	var unclaimed nodeUnclaimed
+
+	if err := node.call(&unclaimed, "getunclaimed", address); err != nil {
+		return nil, err
+	}
+
+	after, err := node.height()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if after != height {
+		return nil, &verifySkip{reason: fmt.Sprintf("node height moved %d -> %d", height, after)}
+	}
+
+	reference := &claim.Reference{Height: height}
+
+	if reference.Available, err = parseNodeGas(unclaimed.Available); err != nil {
+		return nil, err
+	}
+
+	if reference.Unavailable, err = parseNodeGas(unclaimed.Unavailable); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range claimable.Claimable {
+		gas, err := parseNodeGas(entry.Unclaimed)
+
+		if err != nil {
+			return nil, err
+		}
+
+		reference.Claims = append(reference.Claims, &claim.ReferenceClaim{
+			TxID:  entry.TxID,
+			N:     entry.N,
+			Start: entry.StartHeight,
+			End:   entry.EndHeight,
+			Gas:   gas,
+		})
+	}
+
+	return reference, nil
+}
+
+// localDetails the local claim breakdown of address pinned to the height of reference or the local
+// height below it, return the reference pinned to the same height
+type localDetails func(address string, reference *claim.Reference) (*claim.Reference, []*claim.Detail, error)
+
+// verifier compare local claims of sampled addresses with a reference node
+type verifier struct {
+	node      *referenceNode
+	source    string
+	sample    int
+	interval  time.Duration
+	tolerance tx.Fixed8
+}
+
+func newVerifier(cnf *config.Config, upstream *upstreamPool) (*verifier, error) {
+
+	url := cnf.GetString("insight.verify.node", "")
+
+	if url == "" && len(upstream.nodes) > 0 {
+		url = upstream.nodes[0].url
+	}
+
+	// the node's doubles lose the last Fixed8 digit
+	tolerance, err := claim.ParseFixed8(cnf.GetString("insight.verify.tolerance", "0.00000001"))
+
+	if err != nil {
+		return nil, fmt.Errorf("load insight.verify.tolerance err, %s", err)
+	}
+
+	source := cnf.GetString("insight.verify.source", "wallet")
+
+	if source != "wallet" && source != "sync" {
+		return nil, fmt.Errorf("unknown insight.verify.source %s", source)
+	}
+
+	return &verifier{
+		node:      newReferenceNode(url, time.Second*cnf.GetDuration("insight.verify.timeout", 30)),
+		source:    source,
+		sample:    int(cnf.GetInt64("insight.verify.sample", 100)),
+		interval:  time.Minute * cnf.GetDuration("insight.verify.interval", 0),
+		tolerance: tolerance,
+	}, nil
+}
+
+// VerifyReport result of one verification run
+type VerifyReport struct {
+	Checked    int                   `json:"checked"`
+	Mismatches []*claim.Verification `json:"mismatches"`
+	Skipped    int                   `json:"skipped"`
+	Errors     int                   `json:"errors"`
+}
+
+// Rate mismatches of the checked addresses
+func (report *VerifyReport) Rate() float64 {
+	if report.Checked == 0 {
+		return 0
+	}
+
+	return float64(len(report.Mismatches)) / float64(report.Checked)
+}
+
+// verify compare the claims of addresses, mismatches are logged with their per utxo diffs
+func (v *verifier) verify(addresses []string, local localDetails) *VerifyReport {
+
+	report := &VerifyReport{}
+
+	for _, address := range addresses {
+
+		verification, err := v.verifyAddress(address, local)
+
+		if err != nil {
+			if _, ok := err.(*verifySkip); ok {
+				logger.DebugF("verify claim %s skipped, %s", address, err)
+				report.Skipped++
+				verifySkipped.Add(1)
+				continue
+			}
+
+			logger.ErrorF("verify claim %s err, %s", address, err)
+			report.Errors++
+			verifyErrors.Add(1)
+			continue
+		}
+
+		report.Checked++
+		verifyChecked.Add(1)
+
+		if verification.Match() {
+			continue
+		}
+
+		report.Mismatches = append(report.Mismatches, verification)
+		verifyMismatches.Add(1)
+
+		logger.WarnF("claim mismatch %s", verification)
+
+		for _, diff := range verification.Diffs {
+			logger.WarnF("\tutxo %s", diff)
+		}
+	}
+
+	verifyMismatchRate.Set(report.Rate())
+
+	return report
+}
+
+func (v *verifier) verifyAddress(address string, local localDetails) (*claim.Verification, error) {
+
+	reference, err := v.node.reference(address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reference, details, err := local(address, reference)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return claim.Verify(address, details, reference, v.tolerance), nil
+}
+
+// verifyDetails the local claim breakdown of address at the lower of the node and the sys_fee index
+// height. Below the node height the reference is partial, above it the local utxos are rewound
+func (server *Server) verifyDetails(address string, reference *claim.Reference) (*claim.Reference, []*claim.Detail, error) {
+
+	indexed := server.sysfee.Indexed()
+
+	if indexed == -1 {
+		return nil, nil, &verifySkip{reason: "sys_fee index is empty"}
+	}
+
+	reference = reference.At(indexed)
+
+	utxos, err := server.unclaimed(address)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// neo_utxo keeps no claim block, the utxos the node has claimable were claimed after its height
+	if indexed > reference.Height {
+		claimed, err := server.claimedUTXOs(address, reference.Claims)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		utxos = append(utxos, claimed...)
+	}
+
+	source, err := claim.AtHeight(server.sysfee, reference.Height)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, details, err := server.network.Details(claim.RewindUTXOs(utxos, reference.Height), source)
+
+	return reference, details, err
+}
+
+// sampleAddresses pick up to count addresses from neo_wallet or the sync schedule
+func (server *Server) sampleAddresses(count int) ([]string, error) {
+
+	if server.verifier.source == "sync" {
+		scheduled, err := server.syncStore.scheduled(count * 10)
+
+		if err != nil {
+			return nil, err
+		}
+
+		addresses := make([]string, 0, count)
+
+		for _, i := range rand.Perm(len(scheduled)) {
+			if len(addresses) == count {
+				break
+			}
+
+			addresses = append(addresses, scheduled[i])
+		}
+
+		return addresses, nil
+	}
+
+	rows, err := server.engine.QueryString(
+		`select address from (select distinct address from neo_wallet) as wallets order by random() limit ?`, count,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(rows))
+
+	for _, row := range rows {
+		addresses = append(addresses, row["address"])
+	}
+
+	return addresses, nil
+}
+
+// VerifyClaims compare the local claims with the reference node, addresses are sampled if none given
+func (server *Server) VerifyClaims(addresses ...string) (*VerifyReport, error) {
+
+	if len(addresses) == 0 {
+		sampled, err := server.sampleAddresses(server.verifier.sample)
+
+		if err != nil {
+			return nil, err
+		}
+
+		addresses = sampled
+	}
+
+	logger.InfoF("verify %d addresses' claim against %s", len(addresses), server.verifier.node.url)
+
+	report := server.verifier.verify(addresses, server.verifyDetails)
+
+	logger.InfoF(
+		"verified claim checked %d mismatches %d skipped %d errors %d",
+		report.Checked, len(report.Mismatches), report.Skipped, report.Errors,
+	)
+
+	return report, nil
+}
+
+// verifyLoop verify sampled claims every insight.verify.interval minutes, never return
+func (server *Server) verifyLoop() {
+	ticker := time.NewTicker(server.verifier.interval)
+
+	for range ticker.C {
+		if _, err := server.VerifyClaims(); err != nil {
+			logger.ErrorF("verify claims err, %s", err)
+		}
+	}
+}
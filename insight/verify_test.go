@@ -0,0 +1,163 @@
+package insight
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+	"github.com/stretchr/testify/require"
+)
+
+// testNode neo node stand-in answering getblockcount, getclaimable and getunclaimed
+type testNode struct {
+	counts    []int64
+	claimable []map[string]interface{}
+	unclaimed map[string]interface{}
+}
+
+func (node *testNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{"jsonrpc": "2.0", "id": request.ID}
+
+	switch request.Method {
+	case "getblockcount":
+		response["result"] = node.counts[0]
+
+		if len(node.counts) > 1 {
+			node.counts = node.counts[1:]
+		}
+	case "getclaimable":
+		response["result"] = map[string]interface{}{"claimable": node.claimable}
+	case "getunclaimed":
+		response["result"] = node.unclaimed
+	default:
+		response["error"] = map[string]interface{}{"code": -32601, "message": "Method not found"}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// verifyTestDetails local claims of an index at indexed
+func verifyTestDetails(indexed int64) localDetails {
+	blocks := make([]*claim.BlockFee, 0, 3001)
+
+	for i := int64(0); i <= 3000; i++ {
+		blocks = append(blocks, &claim.BlockFee{Block: i, SysFee: tx.Fixed8(i % 3 * 100000000)})
+	}
+
+	return func(address string, reference *claim.Reference) (*claim.Reference, []*claim.Detail, error) {
+		reference = reference.At(indexed)
+
+		height := reference.Height
+
+		source, err := claim.AtHeight(claim.NewMemoryFeeSource(blocks), height)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		utxos := []*rpc.UTXO{
+			{TransactionID: "0x01", Vout: rpc.Vout{N: 0, Value: "100"}, Block: 10, SpentBlock: 1000},
+			{TransactionID: "0x02", Vout: rpc.Vout{N: 1, Value: "5"}, Block: 500, SpentBlock: -1},
+		}
+
+		_, details, err := claim.MainNet.Details(claim.RewindUTXOs(utxos, height), source)
+
+		return reference, details, err
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	OpenLogger()
+
+	local := verifyTestDetails(5000)
+
+	_, details, err := local("", &claim.Reference{Height: 2000})
+
+	require.NoError(t, err)
+
+	unavailable, available := claim.SumDetails(details)
+
+	node := &testNode{
+		counts: []int64{2001},
+		claimable: []map[string]interface{}{{
+			"txid": "0x01", "n": 0, "value": 100, "start_height": 10, "end_height": 1000,
+			"unclaimed": json.Number(claim.FormatFixed8(details[0].Gas)),
+		}},
+		unclaimed: map[string]interface{}{
+			"available":   json.Number(claim.FormatFixed8(available)),
+			"unavailable": json.Number(claim.FormatFixed8(unavailable)),
+		},
+	}
+
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	v := &verifier{node: newReferenceNode(server.URL, 5*time.Second)}
+
+	report := v.verify([]string{"address"}, local)
+
+	require.Equal(t, 1, report.Checked)
+	require.Empty(t, report.Mismatches)
+	require.Equal(t, float64(0), report.Rate())
+
+	// the node counts one more block for the spent utxo
+	node.claimable[0]["end_height"] = 1001
+
+	mismatches := verifyMismatches.Value()
+
+	report = v.verify([]string{"address"}, local)
+
+	require.Len(t, report.Mismatches, 1)
+	require.Equal(t, claim.DiffRange, report.Mismatches[0].Diffs[0].Reason)
+	require.Equal(t, "01:0", report.Mismatches[0].Diffs[0].UTXO)
+	require.Equal(t, float64(1), report.Rate())
+	require.Equal(t, mismatches+1, verifyMismatches.Value())
+	require.Equal(t, float64(1), verifyMismatchRate.Value())
+
+	// a block arrived while querying
+	node.counts = []int64{2001, 2002}
+
+	report = v.verify([]string{"address"}, local)
+
+	require.Equal(t, 1, report.Skipped)
+	require.Equal(t, 0, report.Checked)
+
+	// the local sys_fee source is below the node height
+	node.counts = []int64{5000}
+
+	report = v.verify([]string{"address"}, local)
+
+	require.Equal(t, 1, report.Errors)
+
+	// the index is below the node, the claims the index covers are checked
+	node.counts = []int64{2001}
+	node.claimable[0]["end_height"] = 1000
+
+	report = v.verify([]string{"address"}, verifyTestDetails(1500))
+
+	require.Equal(t, 1, report.Checked)
+	require.Empty(t, report.Mismatches)
+}
+
+func TestVerifyDetailsHeight(t *testing.T) {
+	server := &Server{sysfee: &sysFeeIndex{height: -1}}
+
+	_, _, err := server.verifyDetails("address", &claim.Reference{Height: 2000})
+
+	require.IsType(t, &verifySkip{}, err)
+}
@@ -0,0 +1,123 @@
+package insight
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+// chain tip watcher metrics, published by expvar
+var (
+	watchHeight    = expvar.NewInt("claim_watch_height")
+	watchTouched   = expvar.NewInt("claim_watch_touched")
+	watchRefreshed = expvar.NewInt("claim_watch_refreshed")
+)
+
+// touchedAddresses addresses with utxos created or spent, or transactions, in blocks (from, to]
+func touchedAddresses(engine *xorm.Engine, from, to int64) ([]string, error) {
+
+	rows, err := engine.QueryString(
+		`select address from neo_utxo where create_block > ? and create_block <= ?
+		union select address from neo_utxo where spent_block > ? and spent_block <= ?
+		union select "from" as address from neo_tx where block > ? and block <= ?
+		union select "to" as address from neo_tx where block > ? and block <= ?`,
+		from, to, from, to, from, to, from, to,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(rows))
+
+	for _, row := range rows {
+		if row["address"] != "" {
+			addresses = append(addresses, row["address"])
+		}
+	}
+
+	return addresses, nil
+}
+
+// refreshTouched schedule the refresh of the cached claims of the addresses touched by blocks (from, to]
+func (server *Server) refreshTouched(from, to int64) error {
+
+	touched, err := touchedAddresses(server.engine, from, to)
+
+	if err != nil {
+		return err
+	}
+
+	watchTouched.Add(int64(len(touched)))
+
+	refreshed, err := server.refreshCached(touched)
+
+	if err != nil {
+		return err
+	}
+
+	logger.DebugF("blocks (%d, %d] touched %d addresses, refresh %d cached", from, to, len(touched), refreshed)
+
+	return nil
+}
+
+// refreshCached schedule the refresh of the cached claims of touched, also of the ones syncing now
+// after their lease completes. Return the count of cached addresses
+func (server *Server) refreshCached(touched []string) (int, error) {
+
+	if len(touched) == 0 {
+		return 0, nil
+	}
+
+	cached, err := server.claimCache.Cached(touched)
+
+	if err != nil {
+		return 0, err
+	}
+
+	for _, address := range cached {
+		server.scheduleSync(address)
+	}
+
+	watchRefreshed.Add(int64(len(cached)))
+
+	return len(cached), nil
+}
+
+// watchTip follow the sys_fee index tip, which follows neo_block, every insight.watch.interval and
+// refresh the cached claims the new blocks touched, never return
+func (server *Server) watchTip() {
+
+	last := server.sysfee.Indexed()
+
+	ticker := time.NewTicker(server.watchInterval)
+
+	for range ticker.C {
+		last = server.watchTipOnce(last)
+	}
+}
+
+// watchTipOnce refresh the claims touched since last, return the tip they are refreshed to. The index
+// is loaded by sysfee.Run after the watcher starts, the first loaded height is the start then
+func (server *Server) watchTipOnce(last int64) int64 {
+
+	tip := server.sysfee.Indexed()
+
+	if last == -1 {
+		return tip
+	}
+
+	if tip <= last {
+		return last
+	}
+
+	if err := server.refreshTouched(last, tip); err != nil {
+		logger.ErrorF("refresh claims touched by blocks (%d, %d] err, %s", last, tip, err)
+		return last
+	}
+
+	watchHeight.Set(tip)
+
+	return tip
+}
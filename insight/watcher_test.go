@@ -0,0 +1,108 @@
+package insight
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/inwecrypto/neo-insight/claim"
+	"github.com/inwecrypto/neogo/rpc"
+	"github.com/inwecrypto/neogo/tx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedClaimAt(t *testing.T) {
+	blocks := make([]*claim.BlockFee, 0, 1001)
+
+	for i := int64(0); i <= 1000; i++ {
+		blocks = append(blocks, &claim.BlockFee{Block: i, SysFee: tx.Fixed8(i % 4 * 100000000)})
+	}
+
+	source := claim.NewMemoryFeeSource(blocks)
+
+	utxos := []*rpc.UTXO{
+		{TransactionID: "01", Vout: rpc.Vout{Value: "100"}, Block: 10, SpentBlock: 300},
+		{TransactionID: "02", Vout: rpc.Vout{Value: "7"}, Block: 20, SpentBlock: -1},
+		{TransactionID: "03", Vout: rpc.Vout{Value: "3000"}, Block: 400, SpentBlock: -1},
+	}
+
+	cachedAt := func(height int64) *CachedClaim {
+		pinned, err := claim.AtHeight(source, height)
+		require.NoError(t, err)
+
+		_, details, err := claim.MainNet.Details(utxos, pinned)
+		require.NoError(t, err)
+
+		unavailable, available := claim.SumDetails(details)
+
+		return &CachedClaim{
+			Unclaimed: &rpc.Unclaimed{
+				Available:   claim.FormatFixed8(available),
+				Unavailable: claim.FormatFixed8(unavailable),
+			},
+			Height: height,
+			Weight: claim.MainNet.Weight(details),
+		}
+	}
+
+	cached := cachedAt(500)
+
+	unclaimed, err := cached.at(claim.MainNet, source, 1000)
+	require.NoError(t, err)
+
+	expect := cachedAt(1000).Unclaimed
+
+	require.Equal(t, expect.Unavailable, unclaimed.Unavailable)
+	require.Equal(t, expect.Available, unclaimed.Available)
+	require.NotEqual(t, cached.Unclaimed.Unavailable, unclaimed.Unavailable)
+
+	unclaimed, err = cached.at(claim.MainNet, source, 500)
+	require.NoError(t, err)
+	require.Equal(t, cached.Unclaimed, unclaimed)
+
+	_, err = cached.at(claim.MainNet, source, 1001)
+	require.Error(t, err)
+
+	// claims cached in the old format carry no height and are recomputed
+	var legacy *CachedClaim
+
+	require.NoError(t, json.Unmarshal([]byte(`{"Unavailable": "1", "Available": "0", "Claims": []}`), &legacy))
+	require.Nil(t, legacy.Unclaimed)
+}
+
+func TestWatchTipOnce(t *testing.T) {
+	server := &Server{sysfee: &sysFeeIndex{height: -1}}
+
+	require.Equal(t, int64(-1), server.watchTipOnce(-1))
+
+	// the index loads, the watcher starts from it instead of refreshing every block before
+	server.sysfee.height = 5000
+
+	require.Equal(t, int64(5000), server.watchTipOnce(-1))
+	require.Equal(t, int64(5000), server.watchTipOnce(5000))
+}
+
+func TestRefreshCachedLeased(t *testing.T) {
+	server := newClaimStatusServer()
+
+	require.NoError(t, server.claimCache.Set("a", testCachedClaim(100)))
+
+	server.scheduleSync("a")
+
+	jobs, err := server.syncStore.lease(time.Now(), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	// a block touches a while its job is leased, the refresh runs after the lease
+	refreshed, err := server.refreshCached([]string{"a", "b"})
+	require.NoError(t, err)
+	require.Equal(t, 1, refreshed)
+
+	_, err = server.syncStore.complete(jobs[0], 0, time.Now())
+	require.NoError(t, err)
+
+	jobs, err = server.syncStore.lease(time.Now(), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "a", jobs[0].Address)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"strconv"
 
 	"github.com/dynamicgo/aliyunlog"
 	"github.com/dynamicgo/config"
@@ -42,5 +43,56 @@ func main() {
 		return
 	}
 
-	server.Run()
+	switch flag.Arg(0) {
+	case "":
+		server.Run()
+	case "backfill-sysfee":
+		if err := server.BackfillSysFee(); err != nil {
+			logger.ErrorF("backfill sys_fee index err , %s", err)
+		}
+	case "check-blocks":
+		start, end := int64(0), int64(-1)
+
+		if flag.NArg() > 1 {
+			if start, err = strconv.ParseInt(flag.Arg(1), 10, 64); err != nil {
+				logger.ErrorF("usage: check-blocks [start [end]], invalid start %s", flag.Arg(1))
+				return
+			}
+		}
+
+		if flag.NArg() > 2 {
+			if end, err = strconv.ParseInt(flag.Arg(2), 10, 64); err != nil {
+				logger.ErrorF("usage: check-blocks [start [end]], invalid end %s", flag.Arg(2))
+				return
+			}
+		}
+
+		continuity, err := server.CheckBlocks(start, end)
+
+		if err != nil {
+			logger.ErrorF("check neo_block err , %s", err)
+			return
+		}
+
+		for _, gap := range continuity.Gaps {
+			logger.WarnF("neo_block missing [%d, %d]", gap.From, gap.To)
+		}
+
+		for _, block := range continuity.Duplicates {
+			logger.WarnF("neo_block duplicate %d", block)
+		}
+
+		logger.InfoF("checked %s", continuity)
+	case "verify-claims":
+		report, err := server.VerifyClaims(flag.Args()[1:]...)
+
+		if err != nil {
+			logger.ErrorF("verify claims err , %s", err)
+			return
+		}
+
+		logger.InfoF("mismatch rate %.4f", report.Rate())
+	default:
+		logger.ErrorF("unknown command %s", flag.Arg(0))
+	}
 }